@@ -0,0 +1,192 @@
+package fixedwidth
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// binaryStyle describes how a binary integer (COBOL COMP or COMP-5) field is
+// encoded. Fields of Go's signed kinds are two's complement integers.
+type binaryStyle struct {
+	littleEndian bool
+}
+
+var errBinaryWidth = errors.New("fixedwidth: binary fields must be 1 to 8 bytes wide")
+
+// uint64 decodes the bits of a binary integer.
+func (s binaryStyle) uint64(b []byte) uint64 {
+	var u uint64
+	for i := range b {
+		x := b[i]
+		if s.littleEndian {
+			x = b[len(b)-1-i]
+		}
+		u = u<<8 | uint64(x)
+	}
+	return u
+}
+
+// check reports an error if b is not a binary integer of width bytes, e.g. a field
+// truncated by the end of the data.
+func (s binaryStyle) check(b []byte, width int) error {
+	if width < 1 || width > 8 {
+		return errBinaryWidth
+	}
+	if len(b) != width {
+		return errors.New("fixedwidth: binary field of " + strconv.Itoa(width) +
+			" bytes holds " + strconv.Itoa(len(b)) + " bytes")
+	}
+	return nil
+}
+
+// parse decodes a binary integer of width bytes with the given scale.
+func (s binaryStyle) parse(b []byte, width, scale int, signed bool) (Decimal, bool, error) {
+	if err := s.check(b, width); err != nil {
+		return Decimal{}, false, err
+	}
+	u := s.uint64(b)
+	bits := uint(len(b) * 8)
+	if signed {
+		// Sign extend the value to 64 bits.
+		c := int64(u<<(64-bits)) >> (64 - bits)
+		return Decimal{Coefficient: c, Scale: scale}, true, nil
+	}
+	if int64(u) < 0 {
+		return Decimal{}, false, errDecimalRange
+	}
+	return Decimal{Coefficient: int64(u), Scale: scale}, true, nil
+}
+
+// format encodes the coefficient of d as a binary integer of width bytes.
+func (s binaryStyle) format(d Decimal, width int, signed bool) ([]byte, error) {
+	if width < 1 || width > 8 {
+		return nil, errBinaryWidth
+	}
+	c := d.Coefficient
+	bits := uint(width * 8)
+	if bits < 64 {
+		lo, hi := int64(0), int64(1)<<bits-1
+		if signed {
+			lo, hi = -1<<(bits-1), 1<<(bits-1)-1
+		}
+		if c < lo || c > hi {
+			return nil, &OverflowError{Width: width, Len: binaryLen(c, signed)}
+		}
+	} else if c < 0 && !signed {
+		return nil, errDecimalRange
+	}
+
+	return s.put(uint64(c), width), nil
+}
+
+// put encodes the low width bytes of u.
+func (s binaryStyle) put(u uint64, width int) []byte {
+	b := make([]byte, width)
+	for i := 0; i < width; i++ {
+		x := byte(u >> (8 * uint(width-1-i)))
+		if s.littleEndian {
+			b[width-1-i] = x
+		} else {
+			b[i] = x
+		}
+	}
+	return b
+}
+
+// binaryLen returns the number of bytes needed to hold c.
+func binaryLen(c int64, signed bool) int {
+	n := 1
+	for ; n < 8; n++ {
+		bits := uint(n * 8)
+		if signed && c >= -1<<(bits-1) && c < 1<<(bits-1) {
+			break
+		}
+		if !signed && c >= 0 && c < 1<<bits {
+			break
+		}
+	}
+	return n
+}
+
+// binaryUintLen returns the number of bytes needed to hold u.
+func binaryUintLen(u uint64) int {
+	n := 1
+	for ; n < 8 && u >= 1<<uint(n*8); n++ {
+	}
+	return n
+}
+
+// binarySetter decodes a binary integer field of width bytes with the given scale.
+// Unsigned integers without a scale are decoded directly, so that they may use all
+// 64 bits.
+func binarySetter(t reflect.Type, s binaryStyle, width, scale int, c codecConfig) valueSetter {
+	signed := !isUnsignedKind(t)
+	if !signed && scale == 0 {
+		return binaryUintSetter(s, width, c)
+	}
+	return numericSetter(func(b []byte) (Decimal, bool, error) {
+		return s.parse(b, width, scale, signed)
+	}, c)
+}
+
+// binaryUintSetter decodes a binary unsigned integer field of width bytes.
+func binaryUintSetter(s binaryStyle, width int, c codecConfig) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		b, err := streamBytes(raw, c)
+		if err != nil {
+			return err
+		}
+		if err := s.check(b, width); err != nil {
+			return err
+		}
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		u := s.uint64(b)
+		if v.OverflowUint(u) {
+			if !c.saturate {
+				return errDecimalRange
+			}
+			u = ^uint64(0) >> (64 - v.Type().Bits())
+		}
+		v.SetUint(u)
+		return nil
+	}
+}
+
+// binaryEncoder encodes a binary integer field of width bytes with the given scale.
+// Unsigned integers without a scale are encoded directly, so that they may use all
+// 64 bits.
+func binaryEncoder(t reflect.Type, s binaryStyle, width, scale int, mode roundingMode, c codecConfig) valueEncoder {
+	signed := !isUnsignedKind(t)
+	if !signed && scale == 0 {
+		return binaryUintEncoder(s, width, c)
+	}
+	return numericEncoder(scale, mode, func(d Decimal) ([]byte, error) {
+		return s.format(d, width, signed)
+	}, c)
+}
+
+// binaryUintEncoder encodes a binary unsigned integer field of width bytes.
+func binaryUintEncoder(s binaryStyle, width int, c codecConfig) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nilEncoder(v)
+			}
+			v = v.Elem()
+		}
+		if width < 1 || width > 8 {
+			return rawValue{}, errBinaryWidth
+		}
+		u := v.Uint()
+		if n := binaryUintLen(u); n > width {
+			return rawValue{}, &OverflowError{Width: width, Len: n}
+		}
+		return streamValue(s.put(u, width), c)
+	}
+}
@@ -0,0 +1,141 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	type H struct {
+		Int16  int16   `fixed:"1,2,binary"`
+		Uint32 uint32  `fixed:"3,6,binary=be"`
+		Int32  int32   `fixed:"7,10,binary=le"`
+		Int64  int64   `fixed:"11,18,binary"`
+		Uint8  uint8   `fixed:"19,19,binary"`
+		Scaled float64 `fixed:"20,21,binary,scale=2"`
+		Text   string  `fixed:"22,24"`
+	}
+
+	data := []byte{
+		0xFF, 0xFE, // -2
+		0x01, 0x02, 0x03, 0x04, // 16909060
+		0x20, 0x00, 0x00, 0x80, // -2147483616
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, // 4294967296
+		0xF0,       // 240
+		0x0B, 0xE2, // 30.42
+		'a', ' ', ' ',
+	}
+	want := H{-2, 16909060, -2147483616, 4294967296, 240, 30.42, "a"}
+
+	var have H
+	if err := Unmarshal(data, &have); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if have != want {
+		t.Errorf("Unmarshal() want %+v, have %+v", want, have)
+	}
+
+	o, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, o) {
+		t.Errorf("Marshal() want %X, have %X", data, o)
+	}
+}
+
+func TestBinary_uint64(t *testing.T) {
+	type H struct {
+		Max   uint64  `fixed:"1,8,binary"`
+		Ptr   *uint64 `fixed:"9,16,binary=le"`
+		Small uint16  `fixed:"17,18,binary"`
+	}
+
+	data := []byte{
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80,
+		0xFF, 0xFF,
+	}
+
+	var have H
+	if err := Unmarshal(data, &have); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if have.Max != math.MaxUint64 || have.Ptr == nil || *have.Ptr != 1<<63 || have.Small != math.MaxUint16 {
+		t.Errorf("Unmarshal() want MaxUint64, 1<<63, MaxUint16, have %+v", have)
+	}
+
+	o, err := Marshal(have)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, o) {
+		t.Errorf("Marshal() want %X, have %X", data, o)
+	}
+}
+
+func TestBinary_errors(t *testing.T) {
+	t.Run("overflow", func(t *testing.T) {
+		var v struct {
+			F1 int `fixed:"1,1,binary"`
+		}
+		v.F1 = 128
+		_, err := Marshal(v)
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Width != 1 || overflowErr.Len != 2 {
+			t.Errorf("Marshal() want *OverflowError, have %v", err)
+		}
+	})
+
+	t.Run("too wide", func(t *testing.T) {
+		var v struct {
+			F1 int `fixed:"1,9,binary"`
+		}
+		err := Unmarshal([]byte("123456789"), &v)
+		if !errors.Is(err, errBinaryWidth) {
+			t.Errorf("Unmarshal() want errBinaryWidth, have %v", err)
+		}
+	})
+
+	t.Run("unsigned overflow", func(t *testing.T) {
+		var v struct {
+			F1 uint64 `fixed:"1,2,binary"`
+		}
+		v.F1 = 1 << 16
+		_, err := Marshal(v)
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Width != 2 || overflowErr.Len != 3 {
+			t.Errorf("Marshal() want *OverflowError, have %v", err)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		type H struct {
+			F1 int32  `fixed:"1,2"`
+			F2 int32  `fixed:"3,6,binary"`
+			F3 uint32 `fixed:"7,10,binary"`
+		}
+		for _, data := range [][]byte{
+			[]byte{'1', '2', 0x00, 0x01},
+			[]byte{'1', '2', 0x00, 0x00, 0x00, 0x01, 0x00, 0x01},
+		} {
+			var h H
+			if err := Unmarshal(data, &h); err == nil {
+				t.Errorf("Unmarshal(%X) want error for a truncated field, have %+v", data, h)
+			}
+		}
+	})
+
+	t.Run("small kind", func(t *testing.T) {
+		var v struct {
+			F1 int8 `fixed:"1,2,binary"`
+		}
+		err := Unmarshal([]byte{0x01, 0x00}, &v)
+		var typeErr *UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			t.Errorf("Unmarshal() want *UnmarshalTypeError, have %v", err)
+		}
+	})
+}
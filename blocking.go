@@ -0,0 +1,141 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// The 1014 byte blocking scheme used by clearing files of some card networks, e.g.
+// Mastercard IPM files, wraps the stream of records in blocks of 1012 data bytes
+// followed by a 2 byte separator. The last block is filled up with pad bytes, and
+// whole blocks of pad bytes may follow it.
+const (
+	block1014Size     = 1014
+	block1014DataSize = 1012
+
+	// block1014Pad is both the pad byte and the separator byte, "@" in ASCII and a
+	// space in EBCDIC.
+	block1014Pad = 0x40
+)
+
+// A Block1014Reader removes the 1014 byte blocking from a stream, see
+// NewBlock1014Reader.
+type Block1014Reader struct {
+	r      io.Reader
+	block  [block1014Size]byte
+	buf    []byte // data not yet read
+	held   int    // pad bytes held back after buf
+	offset int64
+	err    error
+}
+
+// NewBlock1014Reader returns a reader that reads the data of the 1014 byte blocks
+// read from r, without the separators. Pad bytes at the end of the stream are
+// dropped, so the last record of the stream must not end with bytes equal to the
+// pad byte, 0x40, unless its framing does not depend on them, e.g. when records are
+// lines.
+//
+// Use it to decode blocked files directly:
+//
+//	dec := fixedwidth.NewDecoder(fixedwidth.NewBlock1014Reader(f))
+//
+// An error is returned for a block that does not end with the separator, and
+// io.ErrUnexpectedEOF for a final block that is shorter than 1014 bytes.
+func NewBlock1014Reader(r io.Reader) *Block1014Reader {
+	return &Block1014Reader{r: r}
+}
+
+// Read implements io.Reader.
+func (r *Block1014Reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fill reads the next block into r.buf. Trailing pad bytes are held back until it
+// is known whether more data follows them.
+func (r *Block1014Reader) fill() {
+	_, err := io.ReadFull(r.r, r.block[:])
+	if err != nil {
+		// Held back pad bytes are dropped at the end of the stream.
+		r.err = err
+		return
+	}
+	if r.block[block1014DataSize] != block1014Pad || r.block[block1014DataSize+1] != block1014Pad {
+		r.err = errors.New("fixedwidth: invalid block separator " +
+			strconv.Quote(string(r.block[block1014DataSize:])) +
+			" at offset " + strconv.FormatInt(r.offset+block1014DataSize, 10))
+		return
+	}
+	r.offset += block1014Size
+
+	data := bytes.TrimRight(r.block[:block1014DataSize], string([]byte{block1014Pad}))
+	if len(data) == 0 {
+		r.held += block1014DataSize
+		return
+	}
+	r.buf = append(r.buf[:0], bytes.Repeat([]byte{block1014Pad}, r.held)...)
+	r.buf = append(r.buf, data...)
+	r.held = block1014DataSize - len(data)
+}
+
+// A Block1014Writer writes a stream in 1014 byte blocks, see NewBlock1014Writer.
+type Block1014Writer struct {
+	w     io.Writer
+	block [block1014Size]byte
+	n     int // data bytes in block
+}
+
+// NewBlock1014Writer returns a writer that writes the data written to it to w in
+// 1014 byte blocks. Close must be called after the last write to pad and write the
+// last block.
+//
+//	bw := fixedwidth.NewBlock1014Writer(f)
+//	err := fixedwidth.NewEncoder(bw).Encode(records)
+//	...
+//	err = bw.Close()
+func NewBlock1014Writer(w io.Writer) *Block1014Writer {
+	return &Block1014Writer{w: w}
+}
+
+// Write implements io.Writer. Only full blocks are written to the underlying writer.
+func (w *Block1014Writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.block[w.n:block1014DataSize], p)
+		w.n += n
+		p = p[n:]
+		if w.n == block1014DataSize {
+			if err := w.writeBlock(); err != nil {
+				return written, err
+			}
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// Close pads and writes the last block. It does not close the underlying writer.
+func (w *Block1014Writer) Close() error {
+	if w.n == 0 {
+		return nil
+	}
+	return w.writeBlock()
+}
+
+func (w *Block1014Writer) writeBlock() error {
+	for i := w.n; i < block1014Size; i++ {
+		w.block[i] = block1014Pad
+	}
+	w.n = 0
+	_, err := w.w.Write(w.block[:])
+	return err
+}
@@ -0,0 +1,82 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlock1014(t *testing.T) {
+	type H struct {
+		Code string `fixed:"1,4"`
+		Text string `fixed:"5,10"`
+	}
+	// 200 lines of 11 bytes span three blocks.
+	var records []H
+	for i := 0; i < 200; i++ {
+		records = append(records, H{"R", strings.Repeat("@", i%7)})
+	}
+	var lines []byte
+	for _, r := range records {
+		lines = append(lines, []byte(r.Code+"   "+r.Text+strings.Repeat(" ", 6-len(r.Text))+"\n")...)
+	}
+
+	buff := new(bytes.Buffer)
+	bw := NewBlock1014Writer(buff)
+	if err := NewEncoder(bw).Encode(records); err != nil {
+		t.Fatalf("Encode() unexpected error: %v", err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	data := buff.Bytes()
+	if len(data) != 3*block1014Size {
+		t.Fatalf("Close() want %d bytes, have %d", 3*block1014Size, len(data))
+	}
+	for i := block1014DataSize; i < len(data); i += block1014Size {
+		if data[i] != '@' || data[i+1] != '@' {
+			t.Errorf("Close() want separator at offset %d, have %q", i, data[i:i+2])
+		}
+	}
+	if want := append(lines[:1012:1012], "@@"...); !bytes.Equal(data[:block1014Size], want) {
+		t.Errorf("Close() want first block %q, have %q", want, data[:block1014Size])
+	}
+
+	// Add a padding block.
+	data = append(data, bytes.Repeat([]byte{'@'}, block1014Size)...)
+	var have []H
+	if err := NewDecoder(NewBlock1014Reader(bytes.NewReader(data))).Decode(&have); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(have, records) {
+		t.Errorf("Decode() want %v, have %v", records, have)
+	}
+
+	t.Run("pad bytes within data", func(t *testing.T) {
+		// The first block ends with pad bytes that are data.
+		data := append(bytes.Repeat([]byte{'x'}, 1000), bytes.Repeat([]byte{'@'}, 14)...)
+		data = append(data, append([]byte("y"), bytes.Repeat([]byte{'@'}, 1013)...)...)
+		have, err := io.ReadAll(NewBlock1014Reader(bytes.NewReader(data)))
+		want := string(bytes.Repeat([]byte{'x'}, 1000)) + strings.Repeat("@", 12) + "y"
+		if err != nil || string(have) != want {
+			t.Errorf("ReadAll() want %q, have %q, %v", want, have, err)
+		}
+	})
+
+	t.Run("invalid separator", func(t *testing.T) {
+		data := bytes.Repeat([]byte{'x'}, block1014Size)
+		if _, err := io.ReadAll(NewBlock1014Reader(bytes.NewReader(data))); err == nil {
+			t.Errorf("ReadAll() want error")
+		}
+	})
+
+	t.Run("short block", func(t *testing.T) {
+		data := bytes.Repeat([]byte{'@'}, block1014Size+10)
+		if _, err := io.ReadAll(NewBlock1014Reader(bytes.NewReader(data))); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("ReadAll() want io.ErrUnexpectedEOF, have %v", err)
+		}
+	})
+}
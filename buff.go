@@ -3,7 +3,6 @@ package fixedwidth
 import (
 	"bytes"
 	"errors"
-	"strings"
 	"unicode/utf8"
 )
 
@@ -23,7 +22,9 @@ func newLineBuilder(len, cap int, fillChar byte) *lineBuilder {
 	data := make([]byte, len, cap)
 
 	// Fill the buffer with the fill character.
-	data[0] = fillChar
+	if len > 0 {
+		data[0] = fillChar
+	}
 	filled := 1
 	for filled < len {
 		copy(data[filled:], data[:filled])
@@ -182,44 +183,65 @@ type rawValue struct {
 	// mapping of codepoint indices into the bytes. So the `codepointIndices[n]` is the
 	// starting position for the n-th codepoint in `bytes`.
 	codepointIndices []int
+	// state holds the settings of the Decoder that the value was read by, which are
+	// not part of the config that setters are built with.
+	state *decodeState
 }
 
-func (r rawValue) trimLeft(cutset string) rawValue {
-	newData := strings.TrimLeft(r.data, cutset)
+func (r rawValue) trimLeft(padChar byte) rawValue {
+	newData := trimLeftByte(r.data, padChar)
 	leftRemovedBytes := len(r.data) - len(newData)
 
 	if r.codepointIndices == nil {
-		return rawValue{data: newData}
+		return rawValue{data: newData, state: r.state}
 	}
 
 	newIndices := r.trimCodepointIndices(leftRemovedBytes, 0)
-	return rawValue{data: newData, codepointIndices: newIndices}
+	return rawValue{data: newData, codepointIndices: newIndices, state: r.state}
 }
 
-func (r rawValue) trimRight(cutset string) rawValue {
-	newData := strings.TrimRight(r.data, cutset)
+func (r rawValue) trimRight(padChar byte) rawValue {
+	newData := trimRightByte(r.data, padChar)
 	rightRemovedBytes := len(r.data) - len(newData)
 
 	if r.codepointIndices == nil {
-		return rawValue{data: newData}
+		return rawValue{data: newData, state: r.state}
 	}
 
 	newIndices := r.trimCodepointIndices(0, rightRemovedBytes)
-	return rawValue{data: newData, codepointIndices: newIndices}
+	return rawValue{data: newData, codepointIndices: newIndices, state: r.state}
 }
 
-func (r rawValue) trim(cutset string) rawValue {
-	leftTrimmed := strings.TrimLeft(r.data, cutset)
+func (r rawValue) trim(padChar byte) rawValue {
+	leftTrimmed := trimLeftByte(r.data, padChar)
 	leftRemovedBytes := len(r.data) - len(leftTrimmed)
-	bothTrimmed := strings.TrimRight(leftTrimmed, cutset)
+	bothTrimmed := trimRightByte(leftTrimmed, padChar)
 	rightRemovedBytes := len(leftTrimmed) - len(bothTrimmed)
 
 	if r.codepointIndices == nil {
-		return rawValue{data: bothTrimmed}
+		return rawValue{data: bothTrimmed, state: r.state}
 	}
 
 	newIndices := r.trimCodepointIndices(leftRemovedBytes, rightRemovedBytes)
-	return rawValue{data: bothTrimmed, codepointIndices: newIndices}
+	return rawValue{data: bothTrimmed, codepointIndices: newIndices, state: r.state}
+}
+
+// trimLeftByte and trimRightByte trim a single byte, which is not required to be a
+// valid UTF-8 character on its own, e.g. an EBCDIC padding character.
+func trimLeftByte(s string, c byte) string {
+	i := 0
+	for i < len(s) && s[i] == c {
+		i++
+	}
+	return s[i:]
+}
+
+func trimRightByte(s string, c byte) string {
+	i := len(s)
+	for i > 0 && s[i-1] == c {
+		i--
+	}
+	return s[:i]
 }
 
 func (r rawValue) trimCodepointIndices(leftRemovedBytes int, rightRemovedBytes int) []int {
@@ -0,0 +1,104 @@
+package fixedwidth
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A bytesEncoding describes how the bytes of a []byte or [N]byte field are written
+// to the field.
+type bytesEncoding int
+
+const (
+	bytesRaw    bytesEncoding = iota // the bytes as-is
+	bytesHex                         // two upper case hexadecimal digits per byte
+	bytesBase64                      // standard base64 with padding
+)
+
+// isBytes reports whether t is a []byte or [N]byte.
+func isBytes(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
+// decode returns the bytes held by the text of a field.
+func (e bytesEncoding) decode(s string) ([]byte, error) {
+	switch e {
+	case bytesHex:
+		return hex.DecodeString(s)
+	case bytesBase64:
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// encode returns the text of a field holding b.
+func (e bytesEncoding) encode(b []byte) string {
+	switch e {
+	case bytesHex:
+		return strings.ToUpper(hex.EncodeToString(b))
+	case bytesBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return string(b)
+}
+
+// bytesSetter decodes a []byte or [N]byte field. Raw bytes are taken from the
+// underlying stream as-is. A blank hex or base64 field sets a slice to nil, and
+// bytes that do not fit an array are reported as an error.
+func bytesSetter(e bytesEncoding, c codecConfig) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		var b []byte
+		var err error
+		if e == bytesRaw {
+			b, err = streamBytes(raw, c)
+		} else {
+			b, err = e.decode(raw.data)
+		}
+		if err != nil {
+			return err
+		}
+
+		if v.Kind() == reflect.Array {
+			if len(b) > v.Len() {
+				return errors.New("fixedwidth: " + strconv.Itoa(len(b)) + " bytes do not fit " + v.Type().String())
+			}
+			for i := 0; i < v.Len(); i++ {
+				var x byte
+				if i < len(b) {
+					x = b[i]
+				}
+				v.Index(i).SetUint(uint64(x))
+			}
+			return nil
+		}
+		if len(b) == 0 && e != bytesRaw {
+			b = nil
+		}
+		v.SetBytes(append([]byte(nil), b...))
+		return nil
+	}
+}
+
+// bytesEncoder encodes a []byte or [N]byte field. Raw bytes shorter than width are
+// padded with zero bytes rather than the padding character.
+func bytesEncoder(e bytesEncoding, width int, c codecConfig) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		// The elements are copied one by one, as reflect.Copy does not accept a named
+		// byte type.
+		b := make([]byte, v.Len())
+		for i := range b {
+			b[i] = byte(v.Index(i).Uint())
+		}
+		if e != bytesRaw {
+			return newRawValue(e.encode(b), c.useCodepointIndices)
+		}
+		if len(b) < width {
+			b = append(b, make([]byte, width-len(b))...)
+		}
+		return streamValue(b, c)
+	}
+}
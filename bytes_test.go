@@ -0,0 +1,107 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	type H struct {
+		Raw    []byte  `fixed:"1,4"`
+		Array  [2]byte `fixed:"5,6"`
+		Hex    []byte  `fixed:"7,10,hex"`
+		Base64 []byte  `fixed:"11,18,base64"`
+		Key    [3]byte `fixed:"19,24,hex"`
+	}
+
+	for _, tt := range []struct {
+		name string
+		data string
+		want H
+	}{
+		{
+			name: "values",
+			data: " \x1c\x85 \x00a1C85AQID/w==00FF10",
+			want: H{
+				Raw:    []byte{' ', 28, 133, ' '},
+				Array:  [2]byte{0, 'a'},
+				Hex:    []byte{0x1c, 0x85},
+				Base64: []byte{1, 2, 3, 255},
+				Key:    [3]byte{0, 0xff, 0x10},
+			},
+		},
+		{
+			name: "padding",
+			data: "ab\x00\x00\x00\x00" + "    " + "        " + "000000",
+			want: H{Raw: []byte{'a', 'b', 0, 0}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var have H
+			if err := Unmarshal([]byte(tt.data), &have); err != nil {
+				t.Fatalf("Unmarshal(%q) unexpected error: %v", tt.data, err)
+			}
+			if !bytes.Equal(have.Raw, tt.want.Raw) || have.Array != tt.want.Array || !bytes.Equal(have.Hex, tt.want.Hex) ||
+				!bytes.Equal(have.Base64, tt.want.Base64) || have.Key != tt.want.Key {
+				t.Errorf("Unmarshal(%q) want %v, have %v", tt.data, tt.want, have)
+			}
+
+			o, err := Marshal(tt.want)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+			if string(o) != tt.data {
+				t.Errorf("Marshal() want %q, have %q", tt.data, o)
+			}
+		})
+	}
+
+	t.Run("short raw value", func(t *testing.T) {
+		o, err := Marshal(H{Raw: []byte{'a', 'b'}})
+		if want := "ab\x00\x00"; err != nil || string(o[:4]) != want {
+			t.Errorf("Marshal() want %q, have %q, %v", want, o[:4], err)
+		}
+	})
+
+	t.Run("named byte type", func(t *testing.T) {
+		type B byte
+		type N struct {
+			Slice []B  `fixed:"1,2"`
+			Array [2]B `fixed:"3,4"`
+			Hex   [2]B `fixed:"5,8,hex"`
+		}
+		data := "ab\x00c01FF"
+		want := N{[]B{'a', 'b'}, [2]B{0, 'c'}, [2]B{1, 0xff}}
+
+		var have N
+		if err := Unmarshal([]byte(data), &have); err != nil {
+			t.Fatalf("Unmarshal(%q) unexpected error: %v", data, err)
+		}
+		if len(have.Slice) != 2 || have.Slice[0] != 'a' || have.Slice[1] != 'b' || have.Array != want.Array || have.Hex != want.Hex {
+			t.Errorf("Unmarshal(%q) want %v, have %v", data, want, have)
+		}
+		o, err := Marshal(want)
+		if err != nil || string(o) != data {
+			t.Errorf("Marshal() want %q, have %q, %v", data, o, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type A struct {
+			Array [2]byte `fixed:"1,3"`
+		}
+		for _, tt := range []struct {
+			name string
+			data string
+			v    interface{}
+		}{
+			{"invalid hex", "      zz", &H{}},
+			{"invalid base64", "          ********", &H{}},
+			{"too long for array", "abc", &A{}},
+		} {
+			if err := Unmarshal([]byte(tt.data), tt.v); err == nil {
+				t.Errorf("%s: Unmarshal(%q) want error", tt.name, tt.data)
+			}
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ianlopshire/go-fixedwidth"
+	"github.com/ianlopshire/go-fixedwidth/copybook"
+)
+
+// A structDef describes a generated struct.
+type structDef struct {
+	name    string
+	comment string
+	fields  []fieldDef
+}
+
+// A fieldDef describes a field of a generated struct.
+type fieldDef struct {
+	name    string
+	typ     string
+	tag     string
+	comment string
+}
+
+// definition is the JSON layout definition format.
+type definition struct {
+	Records []struct {
+		Name    string `json:"name"`
+		Comment string `json:"comment"`
+		Fields  []struct {
+			Name      string   `json:"name"`
+			Start     int      `json:"start"`
+			End       int      `json:"end"`
+			Type      string   `json:"type"`
+			Alignment string   `json:"alignment"`
+			Pad       string   `json:"pad"`
+			Options   []string `json:"options"`
+			Comment   string   `json:"comment"`
+		} `json:"fields"`
+	} `json:"records"`
+}
+
+// goTypes maps the field types of a JSON layout definition to Go types.
+var goTypes = map[string]string{
+	"string":  "string",
+	"bool":    "bool",
+	"int":     "int",
+	"int8":    "int8",
+	"int16":   "int16",
+	"int32":   "int32",
+	"int64":   "int64",
+	"uint":    "uint",
+	"uint8":   "uint8",
+	"uint16":  "uint16",
+	"uint32":  "uint32",
+	"uint64":  "uint64",
+	"float32": "float32",
+	"float64": "float64",
+	"decimal": "fixedwidth.Decimal",
+	"ebcdic":  "fixedwidth.EbcdicString",
+}
+
+func fromJSON(r io.Reader) ([]structDef, error) {
+	var def definition
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&def); err != nil {
+		return nil, err
+	}
+
+	structs := make([]structDef, 0, len(def.Records))
+	for _, rec := range def.Records {
+		s := structDef{name: exportedName(rec.Name), comment: rec.Comment}
+		for _, f := range rec.Fields {
+			typ, ok := goTypes[strings.TrimPrefix(f.Type, "*")]
+			if !ok {
+				return nil, fmt.Errorf("field %s.%s: unknown type %q", rec.Name, f.Name, f.Type)
+			}
+			if strings.HasPrefix(f.Type, "*") {
+				typ = "*" + typ
+			}
+			if f.Start < 1 || f.End < f.Start {
+				return nil, fmt.Errorf("field %s.%s: invalid positions %d-%d", rec.Name, f.Name, f.Start, f.End)
+			}
+			if len(f.Pad) > 1 {
+				return nil, fmt.Errorf("field %s.%s: pad must be a single character", rec.Name, f.Name)
+			}
+
+			tag := []string{strconv.Itoa(f.Start), strconv.Itoa(f.End)}
+			switch {
+			case f.Pad != "":
+				alignment := f.Alignment
+				if alignment == "" {
+					alignment = "default"
+				}
+				tag = append(tag, alignment, tagPad(f.Pad))
+			case f.Alignment != "":
+				tag = append(tag, f.Alignment)
+			}
+			tag = append(tag, f.Options...)
+
+			s.fields = append(s.fields, fieldDef{
+				name:    exportedName(f.Name),
+				typ:     typ,
+				tag:     strings.Join(tag, ","),
+				comment: f.Comment,
+			})
+		}
+		structs = append(structs, s)
+	}
+	return structs, nil
+}
+
+// tagPad returns the padChar argument of a fixed tag for the padding character pad.
+// A space is written as "_", an underscore as "__", and commas and backslashes are
+// escaped.
+func tagPad(pad string) string {
+	switch pad {
+	case " ":
+		return "_"
+	case "_":
+		return "__"
+	case ",", `\`:
+		return `\` + pad
+	}
+	return pad
+}
+
+func fromCopybook(r io.Reader, typeName string) ([]structDef, error) {
+	layouts, err := copybook.ParseAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	structs := make([]structDef, 0, len(layouts))
+	for _, l := range layouts {
+		s := structDef{name: goName(l.Name)}
+		if l.Name == "" {
+			if typeName == "" {
+				return nil, errors.New("record without a name, use -type to name it")
+			}
+			s.name = typeName
+		} else {
+			s.comment = s.name + " is the " + l.Name + " record."
+		}
+
+		for _, f := range l.Fields {
+			typ := "string"
+			comment := f.Name
+			switch {
+			case f.Type == fixedwidth.Alphanumeric:
+			case !f.Signed:
+				typ = "uint64"
+				if f.Scale > 0 {
+					comment += ", in units of 10^-" + strconv.Itoa(f.Scale)
+				}
+			case f.Scale > 0:
+				typ = "fixedwidth.Decimal"
+			default:
+				typ = "int64"
+			}
+			if f.Redefines != "" {
+				comment += ", redefines " + f.Redefines
+			}
+			s.fields = append(s.fields, fieldDef{
+				name:    goName(f.Name),
+				typ:     typ,
+				tag:     f.Tag(),
+				comment: comment,
+			})
+		}
+		structs = append(structs, s)
+	}
+	return structs, nil
+}
+
+// exportedName returns name if it is an exported Go identifier, such as
+// MerchantZIPCode, and converts it with goName otherwise.
+func exportedName(name string) string {
+	if token.IsIdentifier(name) && token.IsExported(name) {
+		return name
+	}
+	return goName(name)
+}
+
+// goName converts a record or field name to an exported Go identifier. The name is
+// split into words at any character that is not a letter or digit, e.g.
+// "CUST-NAME OF CUSTOMER(1)" becomes CustNameOfCustomer1.
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		rs := []rune(strings.ToLower(word))
+		rs[0] = unicode.ToUpper(rs[0])
+		b.WriteString(string(rs))
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "F" + s
+	}
+	return s
+}
+
+// generate returns the formatted source of a file declaring structs.
+func generate(pkg, source string, structs []structDef) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by fixedwidthgen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+
+	usesPackage := false
+	for _, s := range structs {
+		for _, f := range s.fields {
+			usesPackage = usesPackage || strings.Contains(f.typ, "fixedwidth.")
+		}
+	}
+	if usesPackage {
+		b.WriteString("import \"github.com/ianlopshire/go-fixedwidth\"\n\n")
+	}
+
+	types := make(map[string]bool)
+	for _, s := range structs {
+		if types[s.name] {
+			return nil, fmt.Errorf("duplicate type %s", s.name)
+		}
+		types[s.name] = true
+
+		if s.comment != "" {
+			for _, line := range strings.Split(s.comment, "\n") {
+				fmt.Fprintf(&b, "// %s\n", line)
+			}
+		}
+		fmt.Fprintf(&b, "type %s struct {\n", s.name)
+		names := make(map[string]bool)
+		for _, f := range s.fields {
+			if names[f.name] {
+				return nil, fmt.Errorf("duplicate field %s.%s", s.name, f.name)
+			}
+			names[f.name] = true
+
+			// A backquote cannot appear in the raw string literal of the tag.
+			tag := strings.ReplaceAll(strconv.Quote(f.tag), "`", `\x60`)
+			fmt.Fprintf(&b, "\t%s %s `fixed:%s`", f.name, f.typ, tag)
+			if f.comment != "" {
+				fmt.Fprintf(&b, " // %s", f.comment)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n\n")
+	}
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_json(t *testing.T) {
+	const input = `{
+  "records": [
+    {
+      "name": "VISA_TC05_TCR0",
+      "comment": "VISA_TC05_TCR0 is the first record of a TC05 transaction.",
+      "fields": [
+        {"name": "TransactionCode", "start": 1, "end": 2, "type": "int"},
+        {"name": "FloorLimitIndicator", "start": 26, "end": 26, "type": "string", "alignment": "none", "pad": " ", "comment": "disable padding"},
+        {"name": "source-amount", "start": 79, "end": 90, "type": "*decimal", "options": ["overpunch", "scale=2"]},
+        {"name": "Zip", "start": 139, "end": 143, "type": "string", "pad": "0"},
+        {"name": "Underscored", "start": 144, "end": 145, "type": "string", "alignment": "left", "pad": "_"},
+        {"name": "Commas", "start": 146, "end": 147, "type": "int", "alignment": "right", "pad": ","},
+        {"name": "Backquoted", "start": 148, "end": 149, "type": "string", "pad": "` + "`" + `"}
+      ]
+    }
+  ]
+}`
+	want := "// Code generated by fixedwidthgen from tc05.json. DO NOT EDIT.\n" +
+		"\n" +
+		"package visa\n" +
+		"\n" +
+		"import \"github.com/ianlopshire/go-fixedwidth\"\n" +
+		"\n" +
+		"// VISA_TC05_TCR0 is the first record of a TC05 transaction.\n" +
+		"type VISA_TC05_TCR0 struct {\n" +
+		"\tTransactionCode     int                 `fixed:\"1,2\"`\n" +
+		"\tFloorLimitIndicator string              `fixed:\"26,26,none,_\"` // disable padding\n" +
+		"\tSourceAmount        *fixedwidth.Decimal `fixed:\"79,90,overpunch,scale=2\"`\n" +
+		"\tZip                 string              `fixed:\"139,143,default,0\"`\n" +
+		"\tUnderscored         string              `fixed:\"144,145,left,__\"`\n" +
+		"\tCommas              int                 `fixed:\"146,147,right,\\\\,\"`\n" +
+		"\tBackquoted          string              `fixed:\"148,149,default,\\x60\"`\n" +
+		"}\n"
+
+	structs, err := fromJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("fromJSON() unexpected error: %v", err)
+	}
+	have, err := generate("visa", "tc05.json", structs)
+	if err != nil {
+		t.Fatalf("generate() unexpected error: %v", err)
+	}
+	if string(have) != want {
+		t.Errorf("generate() want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestGenerate_copybook(t *testing.T) {
+	const input = `
+       01  CUSTOMER-RECORD.
+           05  CUST-ID       PIC 9(6).
+           05  CUST-NAME     PIC X(20).
+           05  BALANCE       PIC S9(7)V99 COMP-3.
+           05  CREDIT-LIMIT  PIC 9(5)V99.
+           05  HISTORY OCCURS 2.
+               10  ITEMS     PIC S9(4) COMP.
+`
+	want := "// Code generated by fixedwidthgen from customer.cpy. DO NOT EDIT.\n" +
+		"\n" +
+		"package customer\n" +
+		"\n" +
+		"import \"github.com/ianlopshire/go-fixedwidth\"\n" +
+		"\n" +
+		"// CustomerRecord is the CUSTOMER-RECORD record.\n" +
+		"type CustomerRecord struct {\n" +
+		"\tCustId      uint64             `fixed:\"1,6,overpunch\"`           // CUST-ID\n" +
+		"\tCustName    string             `fixed:\"7,26\"`                    // CUST-NAME\n" +
+		"\tBalance     fixedwidth.Decimal `fixed:\"27,31,comp3,scale=2\"`     // BALANCE\n" +
+		"\tCreditLimit uint64             `fixed:\"32,38,overpunch,scale=2\"` // CREDIT-LIMIT, in units of 10^-2\n" +
+		"\tItems1      int64              `fixed:\"39,40,binary\"`            // ITEMS(1)\n" +
+		"\tItems2      int64              `fixed:\"41,42,binary\"`            // ITEMS(2)\n" +
+		"}\n"
+
+	structs, err := fromCopybook(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatalf("fromCopybook() unexpected error: %v", err)
+	}
+	have, err := generate("customer", "customer.cpy", structs)
+	if err != nil {
+		t.Fatalf("generate() unexpected error: %v", err)
+	}
+	if string(have) != want {
+		t.Errorf("generate() want\n%s\nhave\n%s", want, have)
+	}
+
+	t.Run("unnamed record", func(t *testing.T) {
+		const input = "05 F1 PIC X.\n"
+		if _, err := fromCopybook(strings.NewReader(input), ""); err == nil {
+			t.Errorf("fromCopybook() want error")
+		}
+		structs, err := fromCopybook(strings.NewReader(input), "Header")
+		if err != nil || len(structs) != 1 || structs[0].name != "Header" {
+			t.Errorf("fromCopybook() want Header, have %+v, %v", structs, err)
+		}
+	})
+}
+
+func TestGenerate_errors(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input string
+	}{
+		{"unknown type", `{"records": [{"name": "R", "fields": [{"name": "F", "start": 1, "end": 1, "type": "complex128"}]}]}`},
+		{"invalid positions", `{"records": [{"name": "R", "fields": [{"name": "F", "start": 2, "end": 1, "type": "int"}]}]}`},
+		{"unknown key", `{"records": [{"name": "R", "fields": [{"name": "F", "begin": 1}]}]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := fromJSON(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("fromJSON() want error")
+			}
+		})
+	}
+
+	t.Run("duplicate field", func(t *testing.T) {
+		structs := []structDef{{name: "R", fields: []fieldDef{{name: "F", typ: "int", tag: "1,1"}, {name: "F", typ: "int", tag: "2,2"}}}}
+		if _, err := generate("p", "r.json", structs); err == nil {
+			t.Errorf("generate() want error")
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "record.cpy")
+	out := filepath.Join(dir, "record.go")
+	if err := os.WriteFile(input, []byte("01 REC.\n   05 F1 PIC X(3).\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(input, out, "records", "", ""); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+	first, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run(input, out, "records", "", ""); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+	second, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) || !strings.Contains(string(first), "type Rec struct") {
+		t.Errorf("run() want identical output, have\n%s\n%s", first, second)
+	}
+}
@@ -0,0 +1,127 @@
+// Command fixedwidthgen generates Go structs with `fixed` struct tags from record
+// layout definitions.
+//
+// Usage:
+//
+//	fixedwidthgen [-package name] [-o file] [-format json|copybook] [-type name] input
+//
+// The input is either a JSON layout definition or a COBOL copybook. The format is
+// chosen from the extension of the input (".json" is JSON, anything else is a
+// copybook) unless -format is given.
+//
+// A JSON layout definition lists records and their fields:
+//
+//	{
+//	  "records": [
+//	    {
+//	      "name": "Payment",
+//	      "comment": "Payment is a payment detail record.",
+//	      "fields": [
+//	        {"name": "ID", "start": 1, "end": 6, "type": "int"},
+//	        {"name": "Amount", "start": 7, "end": 12, "type": "decimal", "options": ["comp3", "scale=2"]},
+//	        {"name": "Flag", "start": 13, "end": 13, "type": "string", "alignment": "none", "pad": " ", "comment": "disable padding"}
+//	      ]
+//	    }
+//	  ]
+//	}
+//
+// The field types are string, bool, the integer and float types, decimal
+// (fixedwidth.Decimal) and ebcdic (fixedwidth.EbcdicString). Types may be prefixed
+// with "*" for a pointer. The pad is the padding character itself, e.g. "_" pads with
+// underscores.
+//
+// Copybook fields are named after their COBOL data names, e.g. CUST-NAME becomes
+// CustName. Alphanumeric items are strings. Signed numeric items are Decimals if they
+// have decimal places and int64s otherwise. Unsigned numeric items are uint64s, which
+// hold the unscaled amount of items with decimal places, e.g. cents.
+//
+// The output is formatted and depends only on the input, so regenerated files diff
+// cleanly. It is intended to be used with go generate:
+//
+//	//go:generate go run github.com/ianlopshire/go-fixedwidth/cmd/fixedwidthgen -o records.go records.cpy
+//
+// When run by go generate, the package defaults to the package of the file holding
+// the directive.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		pkg      = flag.String("package", os.Getenv("GOPACKAGE"), "package `name` of the generated file")
+		out      = flag.String("o", "", "write the output to `file` instead of stdout")
+		format   = flag.String("format", "", "input format, json or copybook (default from the input extension)")
+		typeName = flag.String("type", "", "struct `name` of a copybook record without a name")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: fixedwidthgen [flags] input")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *out, *pkg, *format, *typeName); err != nil {
+		fmt.Fprintln(os.Stderr, "fixedwidthgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, out, pkg, format, typeName string) error {
+	if pkg == "" {
+		pkg = "main"
+	}
+	if format == "" {
+		format = "copybook"
+		if strings.EqualFold(filepath.Ext(input), ".json") {
+			format = "json"
+		}
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var structs []structDef
+	switch format {
+	case "json":
+		structs, err = fromJSON(f)
+	case "copybook":
+		structs, err = fromCopybook(f, typeName)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", input, err)
+	}
+
+	src, err := generate(pkg, filepath.Base(input), structs)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return writeFile(out, src)
+}
+
+// writeFile writes src to name unless it already holds src, so the modification
+// time of unchanged files is kept.
+func writeFile(name string, src []byte) error {
+	if old, err := os.ReadFile(name); err == nil && bytes.Equal(old, src) {
+		return nil
+	}
+	return os.WriteFile(name, src, 0o644)
+}
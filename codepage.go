@@ -0,0 +1,252 @@
+package fixedwidth
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/indece-official/go-ebcdic"
+)
+
+// A CodePage is a single-byte character set, such as an EBCDIC code page, that is
+// used to translate between encoded bytes and text.
+type CodePage struct {
+	name   string
+	decode [256]rune
+	encode map[rune]byte
+}
+
+// NewCodePage returns a code page that maps each byte to the rune at its index in
+// table. Bytes that are mapped to utf8.RuneError are undefined in the code page.
+//
+// The code page can be used in tags, e.g. `fixed:"1,10,codepage=name"`, once it has
+// been registered with RegisterCodePage.
+func NewCodePage(name string, table [256]rune) *CodePage {
+	cp := &CodePage{
+		name:   name,
+		decode: table,
+		encode: make(map[rune]byte, len(table)),
+	}
+	for i, r := range table {
+		if r == utf8.RuneError {
+			continue
+		}
+		// The first byte mapped to a rune is used to encode it.
+		if _, ok := cp.encode[r]; !ok {
+			cp.encode[r] = byte(i)
+		}
+	}
+	return cp
+}
+
+// Name returns the name of the code page.
+func (cp *CodePage) Name() string {
+	return cp.name
+}
+
+// Decode translates bytes encoded in the code page to text.
+func (cp *CodePage) Decode(b []byte) (string, error) {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		r := cp.decode[c]
+		if r == utf8.RuneError {
+			return "", &CodePageError{CodePage: cp.name, Byte: c}
+		}
+		runes[i] = r
+	}
+	return string(runes), nil
+}
+
+// Encode translates text to bytes encoded in the code page.
+func (cp *CodePage) Encode(s string) ([]byte, error) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		c, ok := cp.encode[r]
+		if !ok {
+			return nil, &CodePageError{CodePage: cp.name, Rune: r, Encoding: true}
+		}
+		b = append(b, c)
+	}
+	return b, nil
+}
+
+// A CodePageError describes a byte or character that has no mapping in a code page.
+type CodePageError struct {
+	CodePage string // name of the code page
+	Byte     byte   // the undefined byte when decoding
+	Rune     rune   // the unmappable character when encoding
+	Encoding bool   // whether the error occurred while encoding
+}
+
+func (e *CodePageError) Error() string {
+	if e.Encoding {
+		return fmt.Sprintf("fixedwidth: character %q (%U) cannot be encoded in code page %s", e.Rune, e.Rune, e.CodePage)
+	}
+	return fmt.Sprintf("fixedwidth: byte 0x%02X is undefined in code page %s", e.Byte, e.CodePage)
+}
+
+// The built-in EBCDIC code pages. They are registered under the names "037", "273",
+// "500", "1047", "1140", "1141", and "1148".
+var (
+	CodePage037  = NewCodePage("037", libraryTable(ebcdic.EBCDIC037))
+	CodePage273  = NewCodePage("273", libraryTable(ebcdic.EBCDIC273))
+	CodePage500  = NewCodePage("500", patchTable(CodePage037.decode, cp500Patch))
+	CodePage1047 = NewCodePage("1047", patchTable(CodePage037.decode, cp1047Patch))
+	CodePage1140 = NewCodePage("1140", patchTable(CodePage037.decode, euroPatch))
+	CodePage1141 = NewCodePage("1141", patchTable(CodePage273.decode, euroPatch))
+	CodePage1148 = NewCodePage("1148", patchTable(CodePage500.decode, euroPatch))
+)
+
+// cp500Patch holds the differences between code page 037 and 500.
+var cp500Patch = map[byte]rune{
+	0x4A: '[', 0x4F: '!', 0x5A: ']', 0x5F: '^', 0xB0: '¢', 0xBA: '¬', 0xBB: '|',
+}
+
+// cp1047Patch holds the differences between code page 037 and 1047.
+var cp1047Patch = map[byte]rune{
+	0x5F: '^', 0xAD: '[', 0xB0: '¬', 0xBA: 'Ý', 0xBB: '¨', 0xBD: ']',
+}
+
+// euroPatch replaces the currency sign with the euro sign.
+var euroPatch = map[byte]rune{
+	0x9F: '€',
+}
+
+func libraryTable(codePage int) [256]rune {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	s, err := ebcdic.Decode(b, codePage)
+	if err != nil {
+		panic(err)
+	}
+	var table [256]rune
+	copy(table[:], []rune(s))
+	return table
+}
+
+func patchTable(table [256]rune, patch map[byte]rune) [256]rune {
+	for b, r := range patch {
+		table[b] = r
+	}
+	return table
+}
+
+var (
+	codePagesMu sync.RWMutex
+	codePages   = map[string]*CodePage{}
+)
+
+func init() {
+	for _, cp := range []*CodePage{
+		CodePage037, CodePage273, CodePage500, CodePage1047, CodePage1140, CodePage1141, CodePage1148,
+	} {
+		RegisterCodePage(cp)
+	}
+}
+
+// RegisterCodePage makes a code page available to the codepage tag option under its
+// name. A code page registered with the same name as an existing one replaces it.
+func RegisterCodePage(cp *CodePage) {
+	codePagesMu.Lock()
+	defer codePagesMu.Unlock()
+	codePages[cp.name] = cp
+}
+
+// LookupCodePage returns the code page registered under name.
+func LookupCodePage(name string) (*CodePage, bool) {
+	codePagesMu.RLock()
+	defer codePagesMu.RUnlock()
+	cp, ok := codePages[name]
+	return cp, ok
+}
+
+// UnknownCodePageError describes a codepage tag option naming a code page that has
+// not been registered.
+type UnknownCodePageError struct {
+	Name string
+}
+
+func (e *UnknownCodePageError) Error() string {
+	return "fixedwidth: unknown code page " + e.Name
+}
+
+// codePageSetter decodes the bytes of a field from cp before setting the value.
+func codePageSetter(t reflect.Type, cp *CodePage, c codecConfig) valueSetter {
+	inner := newValueSetter(t, c)
+	if isEbcdicString(t) {
+		inner = ebcdicTextSetter(t)
+	}
+	return func(v reflect.Value, raw rawValue) error {
+		b, err := streamBytes(raw, c)
+		if err != nil {
+			return err
+		}
+		s, err := cp.Decode(b)
+		if err != nil {
+			return err
+		}
+		return inner(v, rawValue{data: s, state: raw.state})
+	}
+}
+
+// codePageEncoder encodes the text of a value to bytes in cp.
+func codePageEncoder(t reflect.Type, cp *CodePage, c codecConfig) valueEncoder {
+	inner := newValueEncoder(t, c)
+	if isEbcdicString(t) {
+		inner = ebcdicTextEncoder
+	}
+	return func(v reflect.Value) (rawValue, error) {
+		value, err := inner(v)
+		if err != nil {
+			return rawValue{}, err
+		}
+		b, err := cp.Encode(value.data)
+		if err != nil {
+			return rawValue{}, err
+		}
+		return streamValue(b, c)
+	}
+}
+
+// streamBytes returns the bytes of the input a raw value was read from. When the whole
+// stream is translated, the text is encoded back to the stream's code page.
+func streamBytes(raw rawValue, c codecConfig) ([]byte, error) {
+	if c.codePage == nil {
+		return []byte(raw.data), nil
+	}
+	return c.codePage.Encode(raw.data)
+}
+
+// streamValue is the inverse of streamBytes. It returns the raw value that is written
+// to the output as b.
+func streamValue(b []byte, c codecConfig) (rawValue, error) {
+	if c.codePage == nil {
+		return rawValue{data: string(b)}, nil
+	}
+	s, err := c.codePage.Decode(b)
+	if err != nil {
+		return rawValue{}, err
+	}
+	return newRawValue(s, true)
+}
+
+// ebcdicNewLine is the EBCDIC NL character that terminates lines in a translated
+// stream by default.
+const ebcdicNewLine = 0x15
+
+// unknownCodePageSetter and unknownCodePageEncoder report a codepage tag option that
+// names an unregistered code page.
+func unknownCodePageSetter(name string) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		return &UnknownCodePageError{name}
+	}
+}
+
+func unknownCodePageEncoder(name string) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		return rawValue{}, &UnknownCodePageError{name}
+	}
+}
@@ -0,0 +1,194 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCodePage_builtin(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cp   *CodePage
+		text string
+		b    []byte
+	}{
+		{"037", CodePage037, "[A1$]", []byte{0xBA, 0xC1, 0xF1, 0x5B, 0xBB}},
+		{"273", CodePage273, "ÄÖÜ", []byte{0x4A, 0xE0, 0x5A}},
+		{"500", CodePage500, "[!]", []byte{0x4A, 0x4F, 0x5A}},
+		{"1047", CodePage1047, "[^]", []byte{0xAD, 0x5F, 0xBD}},
+		{"1140", CodePage1140, "€", []byte{0x9F}},
+		{"1141", CodePage1141, "€Ä", []byte{0x9F, 0x4A}},
+		{"1148", CodePage1148, "€[", []byte{0x9F, 0x4A}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cp, ok := LookupCodePage(tt.name)
+			if !ok || cp != tt.cp {
+				t.Fatalf("LookupCodePage(%q) not registered", tt.name)
+			}
+
+			b, err := cp.Encode(tt.text)
+			if err != nil {
+				t.Fatalf("Encode() unexpected error: %v", err)
+			}
+			if !bytes.Equal(b, tt.b) {
+				t.Errorf("Encode() want %X, have %X", tt.b, b)
+			}
+
+			text, err := cp.Decode(tt.b)
+			if err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if text != tt.text {
+				t.Errorf("Decode() want %q, have %q", tt.text, text)
+			}
+		})
+	}
+}
+
+func TestCodePage_errors(t *testing.T) {
+	var table [256]rune
+	for i := range table {
+		table[i] = utf8.RuneError
+	}
+	table['A'] = 'A'
+	cp := NewCodePage("test", table)
+
+	_, err := cp.Encode("AB")
+	var cpErr *CodePageError
+	if !errors.As(err, &cpErr) || !cpErr.Encoding || cpErr.Rune != 'B' {
+		t.Errorf("Encode() want *CodePageError for 'B', have %v", err)
+	}
+
+	_, err = cp.Decode([]byte("AB"))
+	if !errors.As(err, &cpErr) || cpErr.Encoding || cpErr.Byte != 'B' {
+		t.Errorf("Decode() want *CodePageError for 'B', have %v", err)
+	}
+
+	if _, err := CodePage037.Encode("日本"); !errors.As(err, &cpErr) {
+		t.Errorf("Encode() want *CodePageError, have %v", err)
+	}
+}
+
+func TestCodePage_tagOption(t *testing.T) {
+	type H struct {
+		Name  string        `fixed:"1,5,codepage=1047"`
+		Text  EbcdicString  `fixed:"6,10,codepage=500"`
+		Count int           `fixed:"11,13,right,0,codepage=037"`
+		Ptr   *EbcdicString `fixed:"14,15,codepage=273"`
+	}
+
+	v := H{Name: "[x]", Text: EbcdicString{"a!"}, Count: 42, Ptr: &EbcdicString{"Ä"}}
+	data := []byte{
+		0xAD, 0xA7, 0xBD, 0x40, 0x40, // "[x]  " in 1047
+		0x81, 0x4F, 0x40, 0x40, 0x40, // "a!   " in 500
+		0xF0, 0xF4, 0xF2, // "042" in 037
+		0x4A, 0x40, // "Ä " in 273
+	}
+
+	have, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, have) {
+		t.Errorf("Marshal() want %X, have %X", data, have)
+	}
+
+	var decoded H
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if decoded.Name != v.Name || decoded.Text != v.Text || decoded.Count != v.Count || *decoded.Ptr != *v.Ptr {
+		t.Errorf("Unmarshal() want %+v, have %+v", v, decoded)
+	}
+
+	t.Run("unmappable character", func(t *testing.T) {
+		_, err := Marshal(H{Name: "日本"})
+		var cpErr *CodePageError
+		if !errors.As(err, &cpErr) {
+			t.Errorf("Marshal() want *CodePageError, have %v", err)
+		}
+	})
+
+	t.Run("unknown code page", func(t *testing.T) {
+		var v struct {
+			F1 string `fixed:"1,5,codepage=nope"`
+		}
+		err := Unmarshal([]byte("abcde"), &v)
+		var cpErr *UnknownCodePageError
+		if !errors.As(err, &cpErr) || cpErr.Name != "nope" {
+			t.Errorf("Unmarshal() want *UnknownCodePageError, have %v", err)
+		}
+	})
+}
+
+func TestRegisterCodePage(t *testing.T) {
+	// A rot13 code page for ASCII letters.
+	var table [256]rune
+	for i := range table {
+		table[i] = rune(i)
+	}
+	for i := 0; i < 26; i++ {
+		table['a'+i] = rune('a' + (i+13)%26)
+	}
+	RegisterCodePage(NewCodePage("rot13", table))
+
+	var v struct {
+		F1 string `fixed:"1,5,codepage=rot13"`
+	}
+	if err := Unmarshal([]byte("uryyb"), &v); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if v.F1 != "hello" {
+		t.Errorf("Unmarshal() want %q, have %q", "hello", v.F1)
+	}
+}
+
+func TestCodePage_stream(t *testing.T) {
+	type H struct {
+		Name   string       `fixed:"1,6"`
+		Amount int          `fixed:"7,11,right,0"`
+		Text   EbcdicString `fixed:"12,14"`
+		Other  string       `fixed:"15,17,codepage=500"`
+	}
+
+	encode := func(s string) []byte {
+		b, err := CodePage037.Encode(s)
+		if err != nil {
+			t.Fatalf("Encode() unexpected error: %v", err)
+		}
+		return b
+	}
+	other, _ := CodePage500.Encode("[!]")
+
+	// The '¢' sign is a single byte in code page 037 but two bytes in UTF-8.
+	line1 := append(encode("¢ foo 00042abc"), other...)
+	line2 := append(encode("bar   00007xy "), other...)
+	data := bytes.Join([][]byte{line1, line2}, []byte{0x15})
+
+	want := []H{
+		{"¢ foo", 42, EbcdicString{"abc"}, "[!]"},
+		{"bar", 7, EbcdicString{"xy"}, "[!]"},
+	}
+
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.SetCodePage(CodePage037)
+	var have []H
+	if err := dec.Decode(&have); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if len(have) != 2 || have[0] != want[0] || have[1] != want[1] {
+		t.Errorf("Decode() want %+v, have %+v", want, have)
+	}
+
+	buff := new(bytes.Buffer)
+	enc := NewEncoder(buff)
+	enc.SetCodePage(CodePage037)
+	if err := enc.Encode(want); err != nil {
+		t.Fatalf("Encode() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, buff.Bytes()) {
+		t.Errorf("Encode() want %X, have %X", data, buff.Bytes())
+	}
+}
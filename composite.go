@@ -0,0 +1,114 @@
+package fixedwidth
+
+import (
+	"reflect"
+)
+
+// compositeSpec returns the struct spec of the value v holds or points to if it is a
+// composite struct.
+func (d *Decoder) compositeSpec(v reflect.Value) (structSpec, bool) {
+	t := indirectType(v.Type())
+	if t.Kind() != reflect.Struct {
+		return structSpec{}, false
+	}
+	ss := cachedStructSpec(t, d.config)
+	return ss, len(ss.records) > 0
+}
+
+// readComposite decodes a group of lines, starting with raw, into the composite
+// struct v holds or points to.
+func (d *Decoder) readComposite(v reflect.Value, ss structSpec, raw rawValue) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	v.Set(reflect.Zero(v.Type()))
+	t := v.Type()
+
+	last := -1
+	for {
+		n := ss.recordIndex(raw)
+		if last >= 0 && n <= last {
+			// The line is not part of this group.
+			d.unreadLine(raw)
+			return nil
+		}
+		if n < 0 {
+			rs := ss.records[0]
+			key := rawValueFromLine(raw, rs.startPos, rs.endPos, format{alignment: alignmentNone})
+			return &UnknownRecordError{Key: key.data}
+		}
+
+		rs := ss.records[n]
+		if err := rs.setter(v.Field(rs.index), raw); err != nil {
+			sf := t.Field(rs.index)
+			return &UnmarshalTypeError{raw.data, sf.Type, t.Name(), sf.Name, err}
+		}
+		last = n
+
+		var (
+			ok  bool
+			err error
+		)
+		if raw, ok, err = d.nextLine(); !ok {
+			return err
+		}
+	}
+}
+
+// recordIndex returns the index of the sub-record raw belongs to, or -1 if it matches
+// none of them.
+func (ss structSpec) recordIndex(raw rawValue) int {
+	for n, rs := range ss.records {
+		key := rawValueFromLine(raw, rs.startPos, rs.endPos, format{alignment: alignmentNone})
+		if key.data == rs.key {
+			return n
+		}
+	}
+	return -1
+}
+
+// compositeRecords returns the sub-records of the composite struct v holds or points
+// to. Nil sub-records are omitted.
+func (e *Encoder) compositeRecords(v reflect.Value) ([]reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	ss := cachedStructSpec(v.Type(), e.config)
+	if len(ss.records) == 0 {
+		return nil, false
+	}
+
+	records := make([]reflect.Value, 0, len(ss.records))
+	for _, rs := range ss.records {
+		f := v.Field(rs.index)
+		if (f.Kind() == reflect.Ptr || f.Kind() == reflect.Interface) && f.IsNil() {
+			continue
+		}
+		records = append(records, f)
+	}
+	return records, true
+}
+
+// writeComposite writes each sub-record of a composite struct as a line.
+func (e *Encoder) writeComposite(records []reflect.Value) error {
+	for i, record := range records {
+		if i > 0 {
+			if err := e.writeLineTerminator(); err != nil {
+				return err
+			}
+		}
+		if err := e.writeLine(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
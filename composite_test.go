@@ -0,0 +1,111 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_composite(t *testing.T) {
+	type Transaction struct {
+		TCR0 VISA_TC05_TCR0  `fixed:"6,6,record=0"`
+		TCR1 *VISA_TC05_TCR1 `fixed:"6,6,record=1"`
+	}
+
+	tcr0, err := os.ReadFile("./visa_tc05_tcr0_test.hex")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	tcr1, err := os.ReadFile("./visa_tc05_tcr1_test.hex")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	data := bytes.Join([][]byte{tcr0, tcr1, tcr0}, []byte("\n"))
+
+	t.Run("slice", func(t *testing.T) {
+		var transactions []Transaction
+		if err := Unmarshal(data, &transactions); err != nil {
+			t.Fatalf("Unmarshal() unexpected error: %v", err)
+		}
+		if len(transactions) != 2 {
+			t.Fatalf("Unmarshal() want 2 transactions, have %d", len(transactions))
+		}
+		if transactions[0].TCR0.AccountNumber != 4830970000162705 {
+			t.Errorf("Unmarshal() TCR0 not decoded: %+v", transactions[0].TCR0)
+		}
+		if transactions[0].TCR1 == nil || transactions[0].TCR1.TransactionComponentSequenceNumber != 1 {
+			t.Errorf("Unmarshal() TCR1 not decoded: %+v", transactions[0].TCR1)
+		}
+		if transactions[1].TCR1 != nil {
+			t.Errorf("Unmarshal() want nil TCR1 in second transaction, have %+v", transactions[1].TCR1)
+		}
+	})
+
+	t.Run("incremental", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(data))
+		var n int
+		for {
+			var transaction Transaction
+			err := dec.Decode(&transaction)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			n++
+		}
+		if n != 2 {
+			t.Errorf("Decode() want 2 transactions, have %d", n)
+		}
+	})
+}
+
+func TestComposite_roundTrip(t *testing.T) {
+	type Header struct {
+		Type string `fixed:"1,1"`
+		Name string `fixed:"2,6"`
+	}
+	type Detail struct {
+		Type   string `fixed:"1,1"`
+		Amount int    `fixed:"2,6,right,0"`
+	}
+	type Group struct {
+		Header  Header  `fixed:"1,1,record=H"`
+		Detail1 *Detail `fixed:"1,1,record=D"`
+	}
+
+	data := []byte("Hfoo  \nD00042\nHbar  ")
+	want := []Group{
+		{Header{"H", "foo"}, &Detail{"D", 42}},
+		{Header{"H", "bar"}, nil},
+	}
+
+	var have []Group
+	if err := Unmarshal(data, &have); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("Unmarshal() want %+v, have %+v", want, have)
+	}
+
+	o, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, o) {
+		t.Errorf("Marshal() want %q, have %q", data, o)
+	}
+
+	t.Run("unknown record", func(t *testing.T) {
+		var have []Group
+		err := Unmarshal([]byte("Hfoo  \nX"), &have)
+		var unknownErr *UnknownRecordError
+		if !errors.As(err, &unknownErr) || unknownErr.Key != "X" {
+			t.Errorf("Unmarshal() want *UnknownRecordError for \"X\", have %v", err)
+		}
+	})
+}
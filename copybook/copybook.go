@@ -0,0 +1,320 @@
+// Package copybook parses COBOL copybooks into fixedwidth record layouts.
+//
+// The supported subset covers the data description entries found in record layouts:
+// PIC X, A and 9 items with S and V, numeric-edited items (decoded as text), USAGE
+// DISPLAY, COMP-3 and COMP, SIGN LEADING and TRAILING (including SEPARATE), OCCURS,
+// REDEFINES and FILLER. VALUE, JUSTIFIED, BLANK WHEN ZERO and SYNCHRONIZED clauses and
+// level 66 and 88 entries are ignored. OCCURS DEPENDING ON is reported as an error, as
+// the fields of a layout have fixed positions.
+//
+// Items that occur more than once are flattened into one field per occurrence, named
+// with a subscript, e.g. "AMOUNT(1)" or "AMOUNT(2,1)". Names that are used by more
+// than one elementary item are qualified by their parent, e.g. "AMOUNT OF TOTALS".
+// FILLER items are omitted.
+package copybook
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ianlopshire/go-fixedwidth"
+)
+
+// A SyntaxError describes a copybook entry that could not be parsed.
+type SyntaxError struct {
+	Line int // line of the copybook, starting at 1
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return "copybook: line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+// Parse parses a copybook that describes a single record.
+func Parse(r io.Reader) (*fixedwidth.Layout, error) {
+	layouts, err := ParseAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(layouts) != 1 {
+		return nil, fmt.Errorf("copybook: want 1 record, have %d", len(layouts))
+	}
+	return layouts[0], nil
+}
+
+// ParseAll parses a copybook and returns a layout for each record (level 01 or 77
+// entry) it describes. Entries before the first level 01 entry form a record without
+// a name.
+func ParseAll(r io.Reader) ([]*fixedwidth.Layout, error) {
+	sentences, err := scan(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		records []*item
+		stack   []*item
+	)
+	for _, s := range sentences {
+		it, err := parseEntry(s)
+		if err != nil {
+			return nil, err
+		}
+		if it == nil {
+			continue
+		}
+
+		if it.level == 1 || it.level == 77 {
+			records = append(records, it)
+			stack = []*item{it}
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= it.level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			root := &item{level: 0, line: it.line, occurs: 1}
+			records = append(records, root)
+			stack = []*item{root}
+		}
+		parent := stack[len(stack)-1]
+		if parent.pic != "" {
+			return nil, &SyntaxError{it.line, "elementary item " + parent.name + " cannot have subordinate items"}
+		}
+		it.inherit(parent)
+		parent.children = append(parent.children, it)
+		stack = append(stack, it)
+	}
+
+	layouts := make([]*fixedwidth.Layout, 0, len(records))
+	for _, rec := range records {
+		if err := rec.layOut(); err != nil {
+			return nil, err
+		}
+		l := &fixedwidth.Layout{Name: rec.name}
+		rec.flatten(&l.Fields, 0, nil, "", duplicateNames(rec))
+		if err := l.Validate(); err != nil {
+			return nil, &SyntaxError{rec.line, err.Error()}
+		}
+		layouts = append(layouts, l)
+	}
+	return layouts, nil
+}
+
+// usage is the USAGE clause of an item.
+type usage int
+
+const (
+	usageUnset usage = iota
+	usageDisplay
+	usagePacked
+	usageBinary
+)
+
+// An item is a data description entry.
+type item struct {
+	level     int
+	name      string // empty for FILLER
+	line      int
+	pic       string
+	usage     usage
+	occurs    int
+	table     bool // has an OCCURS clause
+	redefines string
+
+	signSet      bool
+	signLeading  bool
+	signSeparate bool
+
+	parent   *item
+	children []*item
+
+	// Computed by layOut.
+	fieldType fixedwidth.FieldType
+	digits    int
+	scale     int
+	signed    bool
+	offset    int // relative to the parent
+	size      int // of a single occurrence
+}
+
+// inherit sets the parent of it and applies the USAGE and SIGN clauses of the parent.
+func (it *item) inherit(parent *item) {
+	it.parent = parent
+	if it.usage == usageUnset {
+		it.usage = parent.usage
+	}
+	if !it.signSet && parent.signSet {
+		it.signSet, it.signLeading, it.signSeparate = true, parent.signLeading, parent.signSeparate
+	}
+}
+
+// layOut computes the size and type of it and the offsets of its children.
+func (it *item) layOut() error {
+	if it.pic != "" {
+		return it.layOutElementary()
+	}
+	if len(it.children) == 0 {
+		return &SyntaxError{it.line, "group item " + it.displayName() + " has no subordinate items"}
+	}
+
+	cur := 0
+	for _, child := range it.children {
+		if err := child.layOut(); err != nil {
+			return err
+		}
+		if child.redefines == "" {
+			child.offset = cur
+			cur += child.size * child.occurs
+		} else {
+			target := it.child(child.redefines)
+			if target == nil {
+				return &SyntaxError{child.line, "REDEFINES of unknown item " + child.redefines}
+			}
+			child.offset = target.offset
+		}
+		if end := child.offset + child.size*child.occurs; end > it.size {
+			it.size = end
+		}
+	}
+	return nil
+}
+
+func (it *item) layOutElementary() error {
+	p, err := parsePicture(it.pic)
+	if err != nil {
+		return &SyntaxError{it.line, err.Error()}
+	}
+	if !p.numeric {
+		if it.usage != usageUnset && it.usage != usageDisplay {
+			return &SyntaxError{it.line, "non-numeric item " + it.displayName() + " must have USAGE DISPLAY"}
+		}
+		it.fieldType, it.size = fixedwidth.Alphanumeric, p.size
+		return nil
+	}
+
+	it.digits, it.scale, it.signed = p.digits, p.scale, p.signed
+	switch it.usage {
+	case usageUnset, usageDisplay:
+		it.fieldType, it.size = fixedwidth.ZonedDecimal, p.digits
+		if it.signed && it.signSeparate {
+			it.size++
+		}
+	case usagePacked:
+		it.fieldType, it.size = fixedwidth.PackedDecimal, p.digits/2+1
+	case usageBinary:
+		it.fieldType = fixedwidth.BinaryInteger
+		switch {
+		case p.digits <= 4:
+			it.size = 2
+		case p.digits <= 9:
+			it.size = 4
+		case p.digits <= 18:
+			it.size = 8
+		default:
+			return &SyntaxError{it.line, "binary item " + it.displayName() + " has more than 18 digits"}
+		}
+	}
+	return nil
+}
+
+// child returns the child of it with the given name.
+func (it *item) child(name string) *item {
+	for _, child := range it.children {
+		if child.name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+func (it *item) displayName() string {
+	if it.name == "" {
+		return "FILLER"
+	}
+	return it.name
+}
+
+// flatten appends a field for each occurrence of each named elementary item within it.
+// base is the position of the parent of it, subs are the subscripts of the enclosing
+// occurrences and dups are the names that must be qualified by their parent.
+func (it *item) flatten(fields *[]fixedwidth.LayoutField, base int, subs []int, redefines string, dups map[string]bool) {
+	if it.redefines != "" {
+		redefines = it.redefines
+	}
+	for k := 0; k < it.occurs; k++ {
+		start := base + it.offset + k*it.size
+		s := subs
+		if it.table {
+			s = append(append([]int(nil), subs...), k+1)
+		}
+
+		if it.pic == "" {
+			for _, child := range it.children {
+				child.flatten(fields, start, s, redefines, dups)
+			}
+			continue
+		}
+		if it.name == "" {
+			continue
+		}
+		// The SIGN clause only applies to signed DISPLAY items.
+		zoned := it.signed && it.fieldType == fixedwidth.ZonedDecimal
+		name := it.name
+		if g := namedParent(it); dups[name] && g != "" {
+			name += " OF " + g
+		}
+		*fields = append(*fields, fixedwidth.LayoutField{
+			Name:         name + subscript(s),
+			Start:        start + 1,
+			End:          start + it.size,
+			Type:         it.fieldType,
+			Scale:        it.scale,
+			Signed:       it.signed,
+			SignLeading:  zoned && it.signLeading,
+			SignSeparate: zoned && it.signSeparate,
+			Redefines:    redefines,
+		})
+	}
+}
+
+func subscript(subs []int) string {
+	if len(subs) == 0 {
+		return ""
+	}
+	s := make([]string, len(subs))
+	for i, n := range subs {
+		s[i] = strconv.Itoa(n)
+	}
+	return "(" + strings.Join(s, ",") + ")"
+}
+
+// duplicateNames returns the names used by more than one elementary item of rec.
+func duplicateNames(rec *item) map[string]bool {
+	seen := make(map[string]bool)
+	dups := make(map[string]bool)
+	var walk func(it *item)
+	walk = func(it *item) {
+		if it.pic != "" && it.name != "" {
+			dups[it.name] = seen[it.name]
+			seen[it.name] = true
+		}
+		for _, child := range it.children {
+			walk(child)
+		}
+	}
+	walk(rec)
+	return dups
+}
+
+// namedParent returns the name of the nearest named group that contains it.
+func namedParent(it *item) string {
+	for p := it.parent; p != nil; p = p.parent {
+		if p.name != "" {
+			return p.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package copybook
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ianlopshire/go-fixedwidth"
+)
+
+const customerCopybook = `
+      * Customer master record.
+000100 01  CUSTOMER-RECORD.
+000200     05  CUST-ID            PIC 9(6).
+000300     05  CUST-NAME          PIC X(20).
+000400     05  BALANCE            PIC S9(7)V99 COMP-3.
+000500     05  CREDIT-LIMIT       PIC 9(5)V99.
+000600     05  HISTORY OCCURS 3 TIMES INDEXED BY HIST-IDX.
+000700         10  AMOUNT         PIC S9(5)V99 SIGN IS LEADING.
+000800         10  FILLER         PIC X.
+000900     05  DOB                PIC 9(8).
+001000     05  DOB-R REDEFINES DOB.
+001100         10  DOB-YEAR       PIC 9(4).
+001200         10  DOB-MONTH      PIC 99.
+001300         10  DOB-DAY        PIC 99.
+001400     05  ITEM-COUNT         PIC S9(4) USAGE IS COMP.
+001500     05  STATUS-CODE        PIC X VALUE 'A'.
+001600         88  ACTIVE         VALUE 'A'.
+001700         88  CLOSED         VALUE 'C' 'X'.                        CUST0001
+`
+
+func TestParse(t *testing.T) {
+	l, err := Parse(strings.NewReader(customerCopybook))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if l.Name != "CUSTOMER-RECORD" {
+		t.Errorf("Parse() want name CUSTOMER-RECORD, have %q", l.Name)
+	}
+
+	const (
+		x = fixedwidth.Alphanumeric
+		z = fixedwidth.ZonedDecimal
+		p = fixedwidth.PackedDecimal
+		b = fixedwidth.BinaryInteger
+	)
+	want := []fixedwidth.LayoutField{
+		{Name: "CUST-ID", Start: 1, End: 6, Type: z},
+		{Name: "CUST-NAME", Start: 7, End: 26, Type: x},
+		{Name: "BALANCE", Start: 27, End: 31, Type: p, Scale: 2, Signed: true},
+		{Name: "CREDIT-LIMIT", Start: 32, End: 38, Type: z, Scale: 2},
+		{Name: "AMOUNT(1)", Start: 39, End: 45, Type: z, Scale: 2, Signed: true, SignLeading: true},
+		{Name: "AMOUNT(2)", Start: 47, End: 53, Type: z, Scale: 2, Signed: true, SignLeading: true},
+		{Name: "AMOUNT(3)", Start: 55, End: 61, Type: z, Scale: 2, Signed: true, SignLeading: true},
+		{Name: "DOB", Start: 63, End: 70, Type: z},
+		{Name: "DOB-YEAR", Start: 63, End: 66, Type: z, Redefines: "DOB"},
+		{Name: "DOB-MONTH", Start: 67, End: 68, Type: z, Redefines: "DOB"},
+		{Name: "DOB-DAY", Start: 69, End: 70, Type: z, Redefines: "DOB"},
+		{Name: "ITEM-COUNT", Start: 71, End: 72, Type: b, Signed: true},
+		{Name: "STATUS-CODE", Start: 73, End: 73, Type: x},
+	}
+	if !reflect.DeepEqual(l.Fields, want) {
+		t.Errorf("Parse() want fields\n%+v\nhave\n%+v", want, l.Fields)
+	}
+	if l.Len() != 73 {
+		t.Errorf("Len() want 73, have %d", l.Len())
+	}
+}
+
+func TestParse_decode(t *testing.T) {
+	l, err := Parse(strings.NewReader(customerCopybook))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	data := []byte("000042Jane Doe            \x00\x01\x23\x45\x6D0012345" +
+		"J000100 {000200 A000300 19800102\x00\x07A")
+
+	r := fixedwidth.NewRecord(l)
+	if err := fixedwidth.Unmarshal(data, r); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	want := map[string]interface{}{
+		"CUST-ID":      fixedwidth.Decimal{Coefficient: 42},
+		"CUST-NAME":    "Jane Doe",
+		"BALANCE":      fixedwidth.Decimal{Coefficient: -123456, Scale: 2},
+		"CREDIT-LIMIT": fixedwidth.Decimal{Coefficient: 12345, Scale: 2},
+		"AMOUNT(1)":    fixedwidth.Decimal{Coefficient: -1000100, Scale: 2},
+		"AMOUNT(2)":    fixedwidth.Decimal{Coefficient: 200, Scale: 2},
+		"AMOUNT(3)":    fixedwidth.Decimal{Coefficient: 1000300, Scale: 2},
+		"DOB":          fixedwidth.Decimal{Coefficient: 19800102},
+		"DOB-YEAR":     fixedwidth.Decimal{Coefficient: 1980},
+		"DOB-MONTH":    fixedwidth.Decimal{Coefficient: 1},
+		"DOB-DAY":      fixedwidth.Decimal{Coefficient: 2},
+		"ITEM-COUNT":   fixedwidth.Decimal{Coefficient: 7},
+		"STATUS-CODE":  "A",
+	}
+	if !reflect.DeepEqual(r.Values, want) {
+		t.Errorf("Unmarshal() want %v, have %v", want, r.Values)
+	}
+
+	// The redefining fields are left out, so DOB is encoded as-is.
+	delete(r.Values, "DOB-YEAR")
+	delete(r.Values, "DOB-MONTH")
+	delete(r.Values, "DOB-DAY")
+	have, err := fixedwidth.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if string(have) != string(data) {
+		t.Errorf("Marshal() want %q, have %q", data, have)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	const copybook = `
+01 HEADER.
+   05 REC-TYPE PIC X(2).
+   05 RUN-DATE PIC 9(8).
+01 DETAIL.
+   05 REC-TYPE PIC X(2).
+   05 TOTALS.
+      10 AMOUNT PIC S9(3) COMP-3 OCCURS 2.
+   05 FEES.
+      10 AMOUNT PIC S9(3) COMP-3.
+`
+	layouts, err := ParseAll(strings.NewReader(copybook))
+	if err != nil {
+		t.Fatalf("ParseAll() unexpected error: %v", err)
+	}
+	if len(layouts) != 2 || layouts[0].Name != "HEADER" || layouts[1].Name != "DETAIL" {
+		t.Fatalf("ParseAll() want HEADER and DETAIL, have %+v", layouts)
+	}
+
+	var names []string
+	for _, f := range layouts[1].Fields {
+		names = append(names, f.Name)
+	}
+	want := []string{"REC-TYPE", "AMOUNT OF TOTALS(1)", "AMOUNT OF TOTALS(2)", "AMOUNT OF FEES"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ParseAll() want names %q, have %q", want, names)
+	}
+
+	if _, err := Parse(strings.NewReader(copybook)); err == nil {
+		t.Errorf("Parse() want error for more than one record")
+	}
+}
+
+func TestParse_signSeparate(t *testing.T) {
+	l, err := Parse(strings.NewReader(`
+       01  TOTALS SIGN IS TRAILING SEPARATE CHARACTER.
+           05  DEBITS    PIC S9(3)V99 SIGN LEADING SEPARATE.
+           05  CREDITS   PIC S9(3)V99.
+           05  COUNT     PIC S99 COMP-3.
+`))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	z := fixedwidth.ZonedDecimal
+	want := []fixedwidth.LayoutField{
+		{Name: "DEBITS", Start: 1, End: 6, Type: z, Scale: 2, Signed: true, SignLeading: true, SignSeparate: true},
+		{Name: "CREDITS", Start: 7, End: 12, Type: z, Scale: 2, Signed: true, SignSeparate: true},
+		{Name: "COUNT", Start: 13, End: 14, Type: fixedwidth.PackedDecimal, Signed: true},
+	}
+	if !reflect.DeepEqual(l.Fields, want) {
+		t.Fatalf("Parse() want fields\n%+v\nhave\n%+v", want, l.Fields)
+	}
+
+	data := []byte("-1234000567+\x01\x2C")
+	r := fixedwidth.NewRecord(l)
+	if err := fixedwidth.Unmarshal(data, r); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if have := r.Values["DEBITS"]; have != (fixedwidth.Decimal{Coefficient: -12340, Scale: 2}) {
+		t.Errorf("Unmarshal() want DEBITS -123.40, have %v", have)
+	}
+	if have := r.Values["CREDITS"]; have != (fixedwidth.Decimal{Coefficient: 567, Scale: 2}) {
+		t.Errorf("Unmarshal() want CREDITS 5.67, have %v", have)
+	}
+	o, err := fixedwidth.Marshal(r)
+	if err != nil || string(o) != string(data) {
+		t.Errorf("Marshal() want %q, have %q, %v", data, o, err)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		copybook string
+		line     int
+	}{
+		{"invalid level", "01 REC.\n   55 F1 PIC X.", 2},
+		{"not terminated", "01 REC.\n   05 F1 PIC X", 2},
+		{"unknown clause", "01 REC.\n   05 F1 PIC X FOO.", 2},
+		{"unsupported usage", "01 REC.\n   05 F1 COMP-1.", 2},
+		{"unknown redefines", "01 REC.\n   05 F1 PIC X.\n   05 F2 REDEFINES F3 PIC X.", 3},
+		{"occurs depending on", "01 REC.\n   05 N PIC 9.\n   05 T OCCURS 1 TO 5 DEPENDING ON N PIC X.", 3},
+		{"invalid picture", "01 REC.\n   05 F1 PIC X(.", 2},
+		{"empty group", "01 REC.\n   05 F1.", 2},
+		{"packed text", "01 REC.\n   05 F1 PIC X COMP-3.", 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.copybook))
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) || syntaxErr.Line != tt.line {
+				t.Errorf("Parse() want *SyntaxError on line %d, have %v", tt.line, err)
+			}
+		})
+	}
+}
+
+func TestParsePicture(t *testing.T) {
+	for _, tt := range []struct {
+		pic  string
+		want picture
+	}{
+		{"X(10)", picture{size: 10}},
+		{"XXX", picture{size: 3}},
+		{"9(5)", picture{numeric: true, digits: 5}},
+		{"S9(7)V99", picture{numeric: true, signed: true, digits: 9, scale: 2}},
+		{"S9V9(3)", picture{numeric: true, signed: true, digits: 4, scale: 3}},
+		{"ZZ,ZZ9.99", picture{size: 9}},
+		{"-9(3)", picture{size: 4}},
+	} {
+		t.Run(tt.pic, func(t *testing.T) {
+			have, err := parsePicture(tt.pic)
+			if err != nil {
+				t.Fatalf("parsePicture() unexpected error: %v", err)
+			}
+			if have != tt.want {
+				t.Errorf("parsePicture() want %+v, have %+v", tt.want, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package copybook
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// keywords are the words that start a clause of a data description entry.
+var keywords = map[string]bool{
+	"PIC": true, "PICTURE": true, "USAGE": true, "OCCURS": true, "REDEFINES": true,
+	"SIGN": true, "LEADING": true, "TRAILING": true, "VALUE": true, "VALUES": true,
+	"JUSTIFIED": true, "JUST": true, "BLANK": true, "SYNC": true, "SYNCHRONIZED": true,
+	"GLOBAL": true, "EXTERNAL": true, "INDEXED": true, "ASCENDING": true,
+	"DESCENDING": true, "DEPENDING": true,
+}
+
+// usages maps the words of a USAGE clause to usages. Unsupported usages map to
+// usageUnset.
+var usages = map[string]usage{
+	"DISPLAY":         usageDisplay,
+	"COMP-3":          usagePacked,
+	"COMPUTATIONAL-3": usagePacked,
+	"PACKED-DECIMAL":  usagePacked,
+	"COMP":            usageBinary,
+	"COMPUTATIONAL":   usageBinary,
+	"COMP-4":          usageBinary,
+	"COMPUTATIONAL-4": usageBinary,
+	"COMP-5":          usageBinary,
+	"COMPUTATIONAL-5": usageBinary,
+	"BINARY":          usageBinary,
+	"COMP-1":          usageUnset,
+	"COMPUTATIONAL-1": usageUnset,
+	"COMP-2":          usageUnset,
+	"COMPUTATIONAL-2": usageUnset,
+	"POINTER":         usageUnset,
+	"INDEX":           usageUnset,
+}
+
+// parseEntry parses a data description entry. Nil is returned for entries that do not
+// describe data, i.e. level 66 and 88 entries.
+func parseEntry(s sentence) (*item, error) {
+	p := &entryParser{tokens: s.tokens}
+	errorf := func(msg string) error {
+		return &SyntaxError{s.line, msg}
+	}
+
+	level, err := strconv.Atoi(p.next())
+	if err != nil || level < 1 || level > 88 || (level > 49 && level != 66 && level != 77 && level != 88) {
+		return nil, errorf("invalid level number " + strconv.Quote(s.tokens[0]))
+	}
+	if level == 66 || level == 88 {
+		return nil, nil
+	}
+
+	it := &item{level: level, line: s.line, occurs: 1}
+	if name := p.peek(); name != "" && !keywords[name] && !isUsage(name) {
+		p.next()
+		if name != "FILLER" {
+			it.name = name
+		}
+	}
+
+	for !p.done() {
+		word := p.next()
+		switch {
+		case word == "PIC" || word == "PICTURE":
+			p.skip("IS")
+			if it.pic = p.next(); it.pic == "" {
+				return nil, errorf("missing PICTURE string")
+			}
+		case word == "USAGE" || isUsage(word):
+			if word == "USAGE" {
+				p.skip("IS")
+				word = p.next()
+			}
+			u, ok := usages[word]
+			if !ok {
+				return nil, errorf("invalid USAGE " + strconv.Quote(word))
+			}
+			if u == usageUnset {
+				return nil, errorf("USAGE " + word + " is not supported")
+			}
+			it.usage = u
+		case word == "OCCURS":
+			n, err := strconv.Atoi(p.next())
+			if err != nil || n < 0 {
+				return nil, errorf("invalid OCCURS count")
+			}
+			if p.skip("TO") {
+				if n, err = strconv.Atoi(p.next()); err != nil || n < 1 {
+					return nil, errorf("invalid OCCURS count")
+				}
+			}
+			p.skip("TIMES")
+			it.occurs, it.table = n, true
+		case word == "DEPENDING":
+			return nil, errorf("OCCURS DEPENDING ON is not supported")
+		case word == "ASCENDING" || word == "DESCENDING" || word == "INDEXED":
+			p.skip("KEY")
+			p.skip("IS")
+			p.skip("BY")
+			p.skipOperands()
+		case word == "REDEFINES":
+			if it.redefines = p.next(); it.redefines == "" {
+				return nil, errorf("missing REDEFINES operand")
+			}
+		case word == "SIGN" || word == "LEADING" || word == "TRAILING":
+			if word == "SIGN" {
+				p.skip("IS")
+				word = p.next()
+			}
+			if word != "LEADING" && word != "TRAILING" {
+				return nil, errorf("invalid SIGN clause")
+			}
+			it.signSet, it.signLeading = true, word == "LEADING"
+			if p.skip("SEPARATE") {
+				p.skip("CHARACTER")
+				it.signSeparate = true
+			}
+		case word == "VALUE" || word == "VALUES":
+			p.skip("IS")
+			p.skip("ARE")
+			p.skipOperands()
+		case word == "JUSTIFIED" || word == "JUST":
+			p.skip("RIGHT")
+		case word == "BLANK":
+			p.skip("WHEN")
+			p.next()
+		case word == "SYNC" || word == "SYNCHRONIZED":
+			if !p.skip("LEFT") {
+				p.skip("RIGHT")
+			}
+		case word == "GLOBAL" || word == "EXTERNAL":
+		default:
+			return nil, errorf("unexpected " + strconv.Quote(word))
+		}
+	}
+	return it, nil
+}
+
+func isUsage(word string) bool {
+	_, ok := usages[word]
+	return ok
+}
+
+// entryParser reads the tokens of an entry.
+type entryParser struct {
+	tokens []string
+	i      int
+}
+
+func (p *entryParser) done() bool {
+	return p.i >= len(p.tokens)
+}
+
+func (p *entryParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.i]
+}
+
+func (p *entryParser) next() string {
+	t := p.peek()
+	p.i++
+	return t
+}
+
+// skip consumes the next token if it is the optional word w.
+func (p *entryParser) skip(w string) bool {
+	if p.peek() == w {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// skipOperands consumes tokens up to the next clause.
+func (p *entryParser) skipOperands() {
+	for !p.done() && !keywords[p.peek()] && !isUsage(p.peek()) {
+		p.i++
+	}
+}
+
+// A picture describes a PICTURE string.
+type picture struct {
+	numeric bool
+	signed  bool
+	digits  int
+	scale   int
+	size    int // of a non-numeric item
+}
+
+// parsePicture parses a PICTURE string. Items that are not numeric, including
+// numeric-edited items, are described by their size.
+func parsePicture(pic string) (picture, error) {
+	var symbols []byte
+	for i := 0; i < len(pic); i++ {
+		c := pic[i]
+		if c != '(' {
+			symbols = append(symbols, c)
+			continue
+		}
+		j := strings.IndexByte(pic[i:], ')')
+		if j < 0 || len(symbols) == 0 {
+			return picture{}, errors.New("invalid PICTURE string " + strconv.Quote(pic))
+		}
+		n, err := strconv.Atoi(pic[i+1 : i+j])
+		if err != nil || n < 1 {
+			return picture{}, errors.New("invalid PICTURE string " + strconv.Quote(pic))
+		}
+		for k := 1; k < n; k++ {
+			symbols = append(symbols, symbols[len(symbols)-1])
+		}
+		i += j
+	}
+
+	p := picture{numeric: true}
+	point := false
+	for i, c := range symbols {
+		switch {
+		case c == 'S' && i == 0:
+			p.signed = true
+		case c == 'V' && !point:
+			point = true
+		case c == '9':
+			p.digits++
+			if point {
+				p.scale++
+			}
+		case c == 'P':
+			return picture{}, errors.New("PICTURE symbol P is not supported")
+		default:
+			p.numeric = false
+		}
+	}
+	if p.numeric && p.digits == 0 {
+		return picture{}, errors.New("invalid PICTURE string " + strconv.Quote(pic))
+	}
+	if p.numeric && p.digits > 18 {
+		return picture{}, errors.New("numeric items of more than 18 digits are not supported")
+	}
+	if !p.numeric {
+		p = picture{}
+		for _, c := range symbols {
+			if c != 'S' && c != 'V' {
+				p.size++
+			}
+		}
+	}
+	return p, nil
+}
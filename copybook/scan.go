@@ -0,0 +1,121 @@
+package copybook
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// A sentence holds the tokens of an entry, which ends with a separator period.
+type sentence struct {
+	line   int // line of the first token
+	tokens []string
+}
+
+// scan splits a copybook into sentences. Words are upper-cased; literals are kept as
+// they are, including their quotes.
+//
+// Lines in fixed format, where the first six columns are a sequence number or blank
+// and the seventh is an indicator, have their sequence, indicator and identification
+// areas removed. Comment lines and inline "*>" comments are skipped.
+func scan(r io.Reader) ([]sentence, error) {
+	var (
+		sentences []sentence
+		cur       sentence
+	)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line, ok := content(s.Text())
+		if !ok {
+			continue
+		}
+
+		for i := 0; i < len(line); {
+			c := line[i]
+			switch {
+			case c == ' ' || c == '\t':
+				i++
+				continue
+			case strings.HasPrefix(line[i:], "*>"):
+				i = len(line)
+				continue
+			case (c == ',' || c == ';') && (i+1 == len(line) || isSpace(line[i+1])):
+				i++
+				continue
+			}
+
+			j := i
+			if c == '\'' || c == '"' {
+				// A literal, up to the matching quote.
+				j++
+				for j < len(line) && line[j] != c {
+					j++
+				}
+				if j < len(line) {
+					j++
+				}
+			} else {
+				for j < len(line) && !isSpace(line[j]) {
+					j++
+				}
+			}
+
+			token := line[i:j]
+			end := false
+			if strings.HasSuffix(token, ".") {
+				token, end = token[:len(token)-1], true
+			}
+			if c != '\'' && c != '"' {
+				token = strings.ToUpper(token)
+			}
+			if token != "" {
+				if len(cur.tokens) == 0 {
+					cur.line = n
+				}
+				cur.tokens = append(cur.tokens, token)
+			}
+			if end && len(cur.tokens) > 0 {
+				sentences = append(sentences, cur)
+				cur = sentence{}
+			}
+			i = j
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(cur.tokens) > 0 {
+		return nil, &SyntaxError{cur.line, "entry is not terminated by a period"}
+	}
+	return sentences, nil
+}
+
+// content returns the part of a line that holds source code. False is returned for
+// comment lines.
+func content(line string) (string, bool) {
+	line = strings.TrimRight(line, "\r")
+	if len(line) >= 7 && isSequenceArea(line[:6]) && strings.IndexByte(" *-/Dd", line[6]) >= 0 {
+		if line[6] != ' ' && line[6] != '-' {
+			return "", false
+		}
+		line = line[7:]
+		if len(line) > 65 {
+			line = line[:65]
+		}
+		return line, true
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), "*") {
+		return "", false
+	}
+	return line, true
+}
+
+// isSequenceArea reports whether s is a sequence number or blank.
+func isSequenceArea(s string) bool {
+	digits := strings.Trim(s, "0123456789") == ""
+	return digits || strings.TrimSpace(s) == ""
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
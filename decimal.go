@@ -0,0 +1,337 @@
+package fixedwidth
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number with the value Coefficient * 10^-Scale.
+//
+// Decimal can be used for numeric fields that have an implied scale, e.g.
+// `fixed:"1,6,scale=2"` or `fixed:"1,6,comp3,scale=2"`, and is encoded as a plain
+// decimal number otherwise.
+type Decimal struct {
+	Coefficient int64
+	Scale       int
+}
+
+var decimalType = reflect.TypeOf(Decimal{})
+
+var errDecimalRange = errors.New("fixedwidth: decimal value out of range")
+
+// ParseDecimal parses a decimal number such as "-12.345". The scale of the result is
+// the number of digits after the decimal point.
+func ParseDecimal(s string) (Decimal, error) {
+	var d Decimal
+	digits := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits = s[:i] + s[i+1:]
+		d.Scale = len(s) - i - 1
+	}
+	if digits == "" || digits == "-" || digits == "+" {
+		return Decimal{}, &strconv.NumError{Func: "ParseDecimal", Num: s, Err: strconv.ErrSyntax}
+	}
+	c, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Decimal{}, &strconv.NumError{Func: "ParseDecimal", Num: s, Err: err.(*strconv.NumError).Err}
+	}
+	d.Coefficient = c
+	return d, nil
+}
+
+// String returns the decimal number with Scale digits after the decimal point.
+func (d Decimal) String() string {
+	s := strconv.FormatInt(d.Coefficient, 10)
+	if d.Scale <= 0 {
+		return s + strings.Repeat("0", -d.Scale)
+	}
+
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= d.Scale {
+		s = strings.Repeat("0", d.Scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-d.Scale] + "." + s[len(s)-d.Scale:]
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Empty text is decoded as zero.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// A roundingMode describes how digits that do not fit the scale of a field are
+// rounded.
+type roundingMode int
+
+const (
+	roundHalfUp   roundingMode = iota // to nearest, ties away from zero
+	roundHalfEven                     // to nearest, ties to even
+	roundDown                         // toward zero
+	roundUp                           // away from zero
+	roundFloor                        // toward negative infinity
+	roundCeiling                      // toward positive infinity
+)
+
+// roundingModes maps the values of the round tag option to rounding modes.
+var roundingModes = map[string]roundingMode{
+	"halfup":   roundHalfUp,
+	"halfeven": roundHalfEven,
+	"down":     roundDown,
+	"up":       roundUp,
+	"floor":    roundFloor,
+	"ceiling":  roundCeiling,
+}
+
+// rescale returns d with the given scale. Digits that no longer fit are rounded half
+// away from zero.
+func (d Decimal) rescale(scale int) (Decimal, error) {
+	return d.round(scale, roundHalfUp)
+}
+
+// round returns d with the given scale. Digits that no longer fit are rounded with
+// mode.
+func (d Decimal) round(scale int, mode roundingMode) (Decimal, error) {
+	c := d.Coefficient
+	for s := d.Scale; s < scale; s++ {
+		if c > math.MaxInt64/10 || c < math.MinInt64/10 {
+			return Decimal{}, errDecimalRange
+		}
+		c *= 10
+	}
+	if d.Scale > scale {
+		p := int64(1)
+		for s := scale; s < d.Scale; s++ {
+			if p > math.MaxInt64/10 {
+				p = 0
+				break
+			}
+			p *= 10
+		}
+		q, r := int64(0), c
+		if p != 0 {
+			q, r = c/p, c%p
+		}
+		if r != 0 && roundAway(mode, q, r, p) {
+			if c < 0 {
+				q--
+			} else {
+				q++
+			}
+		}
+		c = q
+	}
+	return Decimal{Coefficient: c, Scale: scale}, nil
+}
+
+// roundAway reports whether the quotient q of the division of a number by p is rounded
+// away from zero, given the non-zero remainder r. A divisor of 0 stands for a divisor
+// that is greater than any remainder.
+func roundAway(mode roundingMode, q, r, p int64) bool {
+	negative := r < 0
+	if negative {
+		r = -r
+	}
+	var half int // the comparison of r with half of p
+	switch {
+	case p == 0 || r < p-r:
+		half = -1
+	case r > p-r:
+		half = 1
+	}
+	switch mode {
+	case roundHalfEven:
+		return half > 0 || half == 0 && q%2 != 0
+	case roundDown:
+		return false
+	case roundUp:
+		return true
+	case roundFloor:
+		return negative
+	case roundCeiling:
+		return !negative
+	}
+	return half >= 0
+}
+
+// isNumericKind reports whether t, or the type it points to, can hold a number
+// decoded by a numericSetter.
+func isNumericKind(t reflect.Type) bool {
+	t = indirectType(t)
+	if t == decimalType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+		reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8,
+		reflect.Float64, reflect.Float32:
+		return true
+	}
+	return false
+}
+
+func isUnsignedKind(t reflect.Type) bool {
+	switch indirectType(t).Kind() {
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		return true
+	}
+	return false
+}
+
+// setDecimal stores d in v. Integers hold the coefficient of d, i.e. an amount of
+// the smallest unit, floats hold its value and Decimals hold d as-is.
+func setDecimal(v reflect.Value, d Decimal) error {
+	if v.Type() == decimalType {
+		v.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		if v.OverflowInt(d.Coefficient) {
+			return errDecimalRange
+		}
+		v.SetInt(d.Coefficient)
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		if d.Coefficient < 0 || v.OverflowUint(uint64(d.Coefficient)) {
+			return errDecimalRange
+		}
+		v.SetUint(uint64(d.Coefficient))
+	case reflect.Float64, reflect.Float32:
+		v.SetFloat(d.Float64())
+	default:
+		return errors.New("fixedwidth: cannot store a decimal in " + v.Type().String())
+	}
+	return nil
+}
+
+// setDecimalSaturating is like setDecimal, but if saturate is true, a coefficient
+// that does not fit an integer v is clamped to the largest or smallest value of its
+// type.
+func setDecimalSaturating(v reflect.Value, d Decimal, saturate bool) error {
+	err := setDecimal(v, d)
+	if err != errDecimalRange || !saturate {
+		return err
+	}
+	bits := v.Type().Bits()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		max := int64(^uint64(0) >> (65 - bits))
+		if d.Coefficient < 0 {
+			v.SetInt(-max - 1)
+		} else {
+			v.SetInt(max)
+		}
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		if d.Coefficient < 0 {
+			v.SetUint(0)
+		} else {
+			v.SetUint(^uint64(0) >> (64 - bits))
+		}
+	default:
+		return err
+	}
+	return nil
+}
+
+// decimalOf returns the value of v as a Decimal with the given scale, rounded with
+// mode. It is the inverse of setDecimal.
+func decimalOf(v reflect.Value, scale int, mode roundingMode) (Decimal, error) {
+	if v.Type() == decimalType {
+		return v.Interface().(Decimal).round(scale, mode)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		return Decimal{Coefficient: v.Int(), Scale: scale}, nil
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		if v.Uint() > math.MaxInt64 {
+			return Decimal{}, errDecimalRange
+		}
+		return Decimal{Coefficient: int64(v.Uint()), Scale: scale}, nil
+	case reflect.Float64, reflect.Float32:
+		// The shortest decimal representation of the float is rounded, so that e.g.
+		// 1.005 is rounded as written.
+		d, err := ParseDecimal(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()))
+		if err != nil {
+			return Decimal{}, errDecimalRange
+		}
+		return d.round(scale, mode)
+	}
+	return Decimal{}, errors.New("fixedwidth: cannot encode " + v.Type().String() + " as a decimal")
+}
+
+// numericSetter returns a setter for a number that is decoded from the bytes of a
+// field by parse. If parse reports that the field is blank, a pointer is set to nil
+// and any other value is left unchanged.
+func numericSetter(parse func(b []byte) (d Decimal, ok bool, err error), c codecConfig) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		b, err := streamBytes(raw, c)
+		if err != nil {
+			return err
+		}
+		d, ok, err := parse(b)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if v.Kind() == reflect.Ptr {
+				return nilSetter(v, raw)
+			}
+			return nil
+		}
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		return setDecimalSaturating(v, d, c.saturate)
+	}
+}
+
+// numericEncoder returns an encoder for a number that is converted to a Decimal with
+// the given scale, rounded with mode, and encoded to bytes by format.
+func numericEncoder(scale int, mode roundingMode, format func(d Decimal) ([]byte, error), c codecConfig) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nilEncoder(v)
+			}
+			v = v.Elem()
+		}
+		d, err := decimalOf(v, scale, mode)
+		if err != nil {
+			return rawValue{}, err
+		}
+		b, err := format(d)
+		if err != nil {
+			return rawValue{}, err
+		}
+		return streamValue(b, c)
+	}
+}
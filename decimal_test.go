@@ -0,0 +1,42 @@
+package fixedwidth
+
+import (
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+	for _, tt := range []struct {
+		s string
+		d Decimal
+	}{
+		{"0", Decimal{0, 0}},
+		{"12.345", Decimal{12345, 3}},
+		{"-0.05", Decimal{-5, 2}},
+		{"100", Decimal{100, 0}},
+	} {
+		t.Run(tt.s, func(t *testing.T) {
+			d, err := ParseDecimal(tt.s)
+			if err != nil || d != tt.d {
+				t.Errorf("ParseDecimal() want %v, have %v (%v)", tt.d, d, err)
+			}
+			if s := tt.d.String(); s != tt.s {
+				t.Errorf("String() want %q, have %q", tt.s, s)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		d     Decimal
+		scale int
+		want  Decimal
+	}{
+		{Decimal{125, 2}, 1, Decimal{13, 1}},
+		{Decimal{-125, 2}, 1, Decimal{-13, 1}},
+		{Decimal{124, 2}, 1, Decimal{12, 1}},
+		{Decimal{12, 1}, 3, Decimal{1200, 3}},
+	} {
+		if have, err := tt.d.rescale(tt.scale); err != nil || have != tt.want {
+			t.Errorf("rescale(%v, %d) want %v, have %v (%v)", tt.d, tt.scale, tt.want, have, err)
+		}
+	}
+}
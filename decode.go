@@ -8,6 +8,8 @@ import (
 	"io"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -29,11 +31,43 @@ type Decoder struct {
 	lineTerminator      []byte
 	done                bool
 	useCodepointIndices bool
+	registry            *Registry
+	config              codecConfig
+	state               decodeState
+
+	// pending holds a line that was read ahead of time and put back with unreadLine.
+	pending *rawValue
+
+	// recordLength is the length of records when they are framed by length rather
+	// than by line terminators. frameLength is the length of the next record and
+	// offset is the number of bytes of input that have been framed.
+	recordLength int
+	frameLength  int
+	offset       int64
+
+	// recordFormat is the framing of records. blockLeft is the number of bytes left in
+	// the current block of RecordFormatVB, and lengthPrefix is the header of records of
+	// RecordFormatLengthPrefix.
+	recordFormat RecordFormat
+	blockLeft    int
+	lengthPrefix LengthPrefix
 
 	lastType       reflect.Type
 	lastValuSetter valueSetter
 }
 
+// decodeState holds the Decoder settings that are used when a value is decoded rather
+// than when its setter is built. Unlike codecConfig, they are not part of the key of
+// cached struct specs, so that changing them does not build new specs.
+type decodeState struct {
+	// layout is used to decode Records that do not have a Layout.
+	layout *Layout
+
+	// reference is the date that partial dates are resolved against, at midnight UTC,
+	// or the zero time to use the current date.
+	reference time.Time
+}
+
 // NewDecoder returns a new decoder that reads from r.
 func NewDecoder(r io.Reader) *Decoder {
 	dec := &Decoder{
@@ -84,6 +118,11 @@ func (e *UnmarshalTypeError) Error() string {
 	return s
 }
 
+// Unwrap returns the original error.
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Cause
+}
+
 // SetUseCodepointIndices configures `Decoder` on whether the indices in the
 // `fixedwidth` struct tags are expressed in terms of bytes (the default
 // behavior) or in terms of UTF-8 decoded codepoints.
@@ -101,6 +140,22 @@ func (d *Decoder) SetUseCodepointIndices(use bool) {
 // In the case that v points to a slice value, Decode will read until
 // the end of its input.
 //
+// In the case that v points to a composite struct, Decode will read a
+// group of consecutive lines into a single value. Each line is decoded
+// into a field of the struct tagged with the record option. The tag's
+// interval holds the discriminator of the line and the option holds its
+// value:
+//
+//	type Transaction struct {
+//		TCR0 VISA_TC05_TCR0  `fixed:"6,6,record=0"`
+//		TCR1 *VISA_TC05_TCR1 `fixed:"6,6,record=1"`
+//	}
+//
+// A line continues the current group if its field is declared after
+// the last one decoded. Otherwise it starts a new group. A line that
+// matches no field ends the group, and an *UnknownRecordError is
+// returned if it is the first line of a group.
+//
 // Currently, the maximum decodable line length is bufio.MaxScanTokenSize-1. ErrTooLong
 // is returned if a line is encountered that too long to decode.
 func (d *Decoder) Decode(v interface{}) error {
@@ -141,6 +196,45 @@ func (d *Decoder) readLines(v reflect.Value) (err error) {
 	return nil
 }
 
+// SetCodePage configures `Decoder` to treat the whole input as text encoded in cp,
+// e.g. CodePage037. Lines are framed, and every field is decoded, from the translated
+// text, so padding characters are given as text and the indices in the `fixedwidth`
+// struct tags are expressed in terms of characters (bytes of the input).
+//
+// SetCodePage also sets the line terminator to the EBCDIC NL character (0x15). Use
+// SetLineTerminator afterwards to choose a different one, e.g. []byte{0x25}.
+//
+// EbcdicString fields hold the translated text as-is. Fields with a codepage tag
+// option are translated from their own code page instead.
+func (d *Decoder) SetCodePage(cp *CodePage) {
+	d.config.codePage = cp
+	d.lineTerminator = []byte{ebcdicNewLine}
+	d.lastType = nil
+}
+
+// SetSaturating configures `Decoder` on whether a number that does not fit the
+// integer type of its field is reported as an *UnmarshalTypeError (the default
+// behavior) or clamped to the largest or smallest value of the type, e.g. "300" is
+// decoded as 127 into an int8 field.
+func (d *Decoder) SetSaturating(saturate bool) {
+	d.config.saturate = saturate
+	d.lastType = nil
+}
+
+// SetReferenceDate sets the date that partial dates, e.g. fields with the time=MMDD or
+// time=YDDD tag options, are resolved against when they have no ref tag option or
+// their reference field is blank. A partial date is resolved to the nearest date that
+// matches it, so that "1231" is decoded as December 31 of the previous year with a
+// reference date of January 2. Only the year, month and day of t are used.
+//
+// The default is the current date. Passing the zero time restores the default.
+func (d *Decoder) SetReferenceDate(t time.Time) {
+	d.state.reference = time.Time{}
+	if !t.IsZero() {
+		d.state.reference = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
 // SetLineTerminator sets the character(s) that will be used to terminate lines.
 //
 // The default value is "\n".
@@ -151,6 +245,21 @@ func (d *Decoder) SetLineTerminator(lineTerminator []byte) {
 }
 
 func (d *Decoder) scan(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	switch {
+	case d.recordFormat == RecordFormatLengthPrefix:
+		advance, token, err = d.scanPrefixed(data, atEOF)
+	case d.recordFormat != RecordFormatLine:
+		advance, token, err = d.scanVariable(data, atEOF)
+	case d.recordLength != 0:
+		advance, token, err = d.scanRecord(data, atEOF)
+	default:
+		advance, token, err = d.scanLine(data, atEOF)
+	}
+	d.offset += int64(advance)
+	return advance, token, err
+}
+
+func (d *Decoder) scanLine(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if atEOF && len(data) == 0 {
 		return 0, nil, nil
 	}
@@ -169,30 +278,81 @@ func (d *Decoder) scan(data []byte, atEOF bool) (advance int, token []byte, err
 // readLine reads the next line of data. False is returned if there is no remaining data
 // to read.
 func (d *Decoder) readLine(v reflect.Value) (err error, ok bool) {
-	ok = d.scanner.Scan()
+	if err := d.setFrameLength(v); err != nil {
+		return err, false
+	}
+	rawValue, ok, err := d.nextLine()
 	if !ok {
-		if d.scanner.Err() != nil {
-			return d.scanner.Err(), false
+		return err, false
+	}
+
+	if ss, ok := d.compositeSpec(v); ok {
+		return d.readComposite(v, ss, rawValue), true
+	}
+	if d.registry != nil {
+		if target := reflect.Indirect(v); target.Kind() == reflect.Interface {
+			return d.registeredSetter(target, rawValue), true
 		}
+	}
 
-		d.done = true
-		return nil, false
+	t := v.Type()
+	if t != d.lastType {
+		d.lastValuSetter = newValueSetter(t, d.config)
+		d.lastType = t
 	}
+	return valueError(t, rawValue, d.lastValuSetter(v, rawValue)), true
+}
 
-	line := string(d.scanner.Bytes())
+// valueError wraps err, an error decoding raw into a value of type t, in an
+// *UnmarshalTypeError like the errors of struct fields. The errors of structs that
+// are decoded field by field, which wrap the errors of their fields, are returned
+// as-is.
+func valueError(t reflect.Type, raw rawValue, err error) error {
+	var typeErr *UnmarshalTypeError
+	if err == nil || errors.As(err, &typeErr) {
+		return err
+	}
+	t = indirectType(t)
+	if t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return err
+	}
+	return &UnmarshalTypeError{Value: raw.data, Type: t, Cause: err}
+}
 
-	rawValue, err := newRawValue(line, d.useCodepointIndices)
-	if err != nil {
-		return err, false
+// nextLine returns the next line of input, including a line that was put back with
+// unreadLine. False is returned if there is no remaining data to read.
+func (d *Decoder) nextLine() (line rawValue, ok bool, err error) {
+	if d.pending != nil {
+		line, d.pending = *d.pending, nil
+		return line, true, nil
 	}
-	t := v.Type()
-	if t == d.lastType {
-		return d.lastValuSetter(v, rawValue), true
+	if !d.scanner.Scan() {
+		if err := d.scanner.Err(); err != nil {
+			return rawValue{}, false, err
+		}
+
+		d.done = true
+		return rawValue{}, false, nil
+	}
+
+	if cp := d.config.codePage; cp != nil {
+		// The whole line is translated, so indices are expressed in codepoints of the
+		// translated text, which correspond to the bytes of the input.
+		text, err := cp.Decode(d.scanner.Bytes())
+		if err != nil {
+			return rawValue{}, false, err
+		}
+		line, err = newRawValue(text, true)
+	} else {
+		line, err = newRawValue(string(d.scanner.Bytes()), d.useCodepointIndices)
 	}
-	valueSetter := newValueSetter(t)
-	d.lastType = t
-	d.lastValuSetter = valueSetter
-	return valueSetter(v, rawValue), true
+	line.state = &d.state
+	return line, err == nil, err
+}
+
+// unreadLine puts a line back so that it is returned by the next call to nextLine.
+func (d *Decoder) unreadLine(line rawValue) {
+	d.pending = &line
 }
 
 func rawValueFromLine(value rawValue, startPos, endPos int, format format) rawValue {
@@ -201,23 +361,23 @@ func rawValueFromLine(value rawValue, startPos, endPos int, format format) rawVa
 	switch format.alignment {
 	case left: // Aligned left, so trim from right side.
 		trimFunc = func(r rawValue) rawValue {
-			return r.trimRight(string(format.padChar))
+			return r.trimRight(format.padChar)
 		}
 	case right: // Aligned right, so trim from left side.
 		trimFunc = func(r rawValue) rawValue {
-			return r.trimLeft(string(format.padChar))
+			return r.trimLeft(format.padChar)
 		}
 	case alignmentNone:
 		trimFunc = func(r rawValue) rawValue { return r }
 	default:
 		trimFunc = func(r rawValue) rawValue {
-			return r.trim(string(format.padChar))
+			return r.trim(format.padChar)
 		}
 	}
 
 	if value.codepointIndices != nil {
 		if len(value.codepointIndices) == 0 || startPos > len(value.codepointIndices) {
-			return rawValue{data: ""}
+			return rawValue{data: "", state: value.state}
 		}
 		var relevantIndices []int
 		var lineData string
@@ -240,15 +400,15 @@ func rawValueFromLine(value rawValue, startPos, endPos int, format format) rawVa
 			}
 		}
 
-		return trimFunc(rawValue{data: lineData, codepointIndices: newIndices})
+		return trimFunc(rawValue{data: lineData, codepointIndices: newIndices, state: value.state})
 	} else {
 		if len(value.data) == 0 || startPos > len(value.data) {
-			return rawValue{data: ""}
+			return rawValue{data: "", state: value.state}
 		}
 		if endPos > len(value.data) {
 			endPos = len(value.data)
 		}
-		return trimFunc(rawValue{data: value.data[startPos-1 : endPos]})
+		return trimFunc(rawValue{data: value.data[startPos-1 : endPos], state: value.state})
 	}
 }
 
@@ -256,7 +416,14 @@ type valueSetter func(v reflect.Value, raw rawValue) error
 
 var textUnmarshalerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
 
-func newValueSetter(t reflect.Type) valueSetter {
+func newValueSetter(t reflect.Type, c codecConfig) valueSetter {
+	if t == recordType {
+		return recordSetter(c)
+	}
+	if c.codePage != nil && t == ebcdicStringType {
+		// The whole stream is already translated.
+		return ebcdicTextSetter(t)
+	}
 	if t.Implements(textUnmarshalerType) {
 		return textUnmarshalerSetter(t, false)
 	}
@@ -266,36 +433,75 @@ func newValueSetter(t reflect.Type) valueSetter {
 
 	switch t.Kind() {
 	case reflect.Ptr:
-		return ptrSetter(t)
+		return ptrSetter(t, c)
 	case reflect.Interface:
-		return interfaceSetter
+		return interfaceSetter(c)
 	case reflect.Struct:
-		return structSetter(t)
+		return structSetter(t, c)
 	case reflect.String:
 		return stringSetter
 	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
-		return intSetter
+		return intSetter(c.saturate)
 	case reflect.Float32:
 		return floatSetter(32)
 	case reflect.Float64:
 		return floatSetter(64)
 	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
-		return uintSetter
+		return uintSetter(c.saturate)
 	case reflect.Bool:
 		return boolSetter
+	case reflect.Slice, reflect.Array:
+		if isBytes(t) {
+			return bytesSetter(bytesRaw, c)
+		}
 	}
 	return unknownSetter
 }
 
-func structSetter(t reflect.Type) valueSetter {
-	spec := cachedStructSpec(t)
+func structSetter(t reflect.Type, c codecConfig) valueSetter {
+	ss := cachedStructSpec(t, c)
 	return func(v reflect.Value, raw rawValue) error {
+		if ss.err != nil {
+			return ss.err
+		}
+		spec, err := ss.forLine(func(i int, d *dependingSpec, countSpec fieldSpec) (int, error) {
+			count := reflect.New(d.countType).Elem()
+			err := countSpec.setter(count, rawValueFromLine(raw, countSpec.startPos, countSpec.endPos, countSpec.format))
+			return d.count(count), err
+		})
+		if err != nil {
+			return err
+		}
+		set := func(i int, fieldSpec fieldSpec) error {
+			rawValue := rawValueFromLine(raw, fieldSpec.startPos, fieldSpec.endPos, fieldSpec.format)
+			setter := fieldSpec.setter
+			if fieldSpec.currency != nil {
+				setter = fieldSpec.currency.setterFor(spec, raw)
+			}
+			if fieldSpec.reference != nil {
+				setter = fieldSpec.reference.setterFor(spec, raw)
+			}
+			return setter(v.Field(i), rawValue)
+		}
 		for i, fieldSpec := range spec.fieldSpecs {
-			if !fieldSpec.ok {
+			if !fieldSpec.ok || fieldSpec.when != nil {
 				continue
 			}
-			rawValue := rawValueFromLine(raw, fieldSpec.startPos, fieldSpec.endPos, fieldSpec.format)
-			err := fieldSpec.setter(v.Field(i), rawValue)
+			if err := set(i, fieldSpec); err != nil {
+				sf := t.Field(i)
+				return &UnmarshalTypeError{raw.data, sf.Type, t.Name(), sf.Name, err}
+			}
+		}
+		// Alternatives are decoded after the fields that select them. Inactive
+		// alternatives are set to their zero value.
+		for _, i := range spec.alternatives {
+			fieldSpec := spec.fieldSpecs[i]
+			active, err := fieldSpec.when.active(spec, v, raw)
+			if err == nil && active {
+				err = set(i, fieldSpec)
+			} else if err == nil {
+				v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+			}
 			if err != nil {
 				sf := t.Field(i)
 				return &UnmarshalTypeError{raw.data, sf.Type, t.Name(), sf.Name, err}
@@ -341,12 +547,14 @@ func textUnmarshalerSetter(t reflect.Type, shouldAddr bool) valueSetter {
 	}
 }
 
-func interfaceSetter(v reflect.Value, raw rawValue) error {
-	return newValueSetter(v.Elem().Type())(v.Elem(), raw)
+func interfaceSetter(c codecConfig) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		return newValueSetter(v.Elem().Type(), c)(v.Elem(), raw)
+	}
 }
 
-func ptrSetter(t reflect.Type) valueSetter {
-	innerSetter := newValueSetter(t.Elem())
+func ptrSetter(t reflect.Type, c codecConfig) valueSetter {
+	innerSetter := newValueSetter(t.Elem(), c)
 	return func(v reflect.Value, raw rawValue) error {
 		if len(raw.data) <= 0 {
 			return nilSetter(v, raw)
@@ -363,16 +571,21 @@ func stringSetter(v reflect.Value, raw rawValue) error {
 	return nil
 }
 
-func intSetter(v reflect.Value, raw rawValue) error {
-	if len(raw.data) < 1 {
+// intSetter decodes an integer that must fit the size of the field's type. If
+// saturate is true, integers that do not fit are clamped instead.
+func intSetter(saturate bool) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		if len(raw.data) < 1 {
+			return nil
+		}
+		i, err := strconv.ParseInt(raw.data, 10, v.Type().Bits())
+		if err != nil && !(saturate && errors.Is(err, strconv.ErrRange)) {
+			return err
+		}
+		// ParseInt returns the nearest value that fits for an out of range integer.
+		v.SetInt(i)
 		return nil
 	}
-	i, err := strconv.Atoi(raw.data)
-	if err != nil {
-		return err
-	}
-	v.SetInt(int64(i))
-	return nil
 }
 
 func floatSetter(bitSize int) valueSetter {
@@ -389,16 +602,32 @@ func floatSetter(bitSize int) valueSetter {
 	}
 }
 
-func uintSetter(v reflect.Value, raw rawValue) error {
-	if len(raw.data) < 1 {
+// uintSetter decodes an unsigned integer that must fit the size of the field's type.
+// If saturate is true, integers that do not fit, including negative integers, are
+// clamped instead.
+func uintSetter(saturate bool) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		if len(raw.data) < 1 {
+			return nil
+		}
+		i, err := strconv.ParseUint(raw.data, 10, v.Type().Bits())
+		if err != nil && saturate {
+			switch {
+			case errors.Is(err, strconv.ErrRange):
+				// ParseUint returns the largest value that fits.
+				err = nil
+			case strings.HasPrefix(raw.data, "-"):
+				if _, perr := strconv.ParseInt(raw.data, 10, 64); perr == nil || errors.Is(perr, strconv.ErrRange) {
+					i, err = 0, nil
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
 		return nil
 	}
-	i, err := strconv.ParseUint(raw.data, 10, 64)
-	if err != nil {
-		return err
-	}
-	v.SetUint(uint64(i))
-	return nil
 }
 
 func boolSetter(v reflect.Value, raw rawValue) error {
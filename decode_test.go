@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"encoding"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -295,6 +297,9 @@ func TestNewValueSetter(t *testing.T) {
 		{"int16", []byte("1"), int16(1), false},
 		{"int32", []byte("1"), int32(1), false},
 		{"int64", []byte("1"), int64(1), false},
+		{"int8 overflow", []byte("300"), int8(0), true},
+		{"int16 overflow", []byte("-40000"), int16(0), true},
+		{"int32 overflow", []byte("2147483648"), int32(0), true},
 
 		{"uint", []byte("1"), uint(1), false},
 		{"uint zero", []byte("0"), uint(0), false},
@@ -309,6 +314,8 @@ func TestNewValueSetter(t *testing.T) {
 		{"uint16", []byte("1"), uint16(1), false},
 		{"uint32", []byte("1"), uint32(1), false},
 		{"uint64", []byte("1"), uint64(1), false},
+		{"uint8 overflow", []byte("256"), uint8(0), true},
+		{"uint16 overflow", []byte("65536"), uint16(0), true},
 
 		{"bool negative", []byte("false"), bool(false), false},
 		{"bool positive", []byte("true"), bool(true), false},
@@ -326,7 +333,7 @@ func TestNewValueSetter(t *testing.T) {
 			// ensure we have an addressable target
 			var i = reflect.Indirect(reflect.New(reflect.TypeOf(tt.expected)))
 
-			err := newValueSetter(i.Type())(i, rawValue{data: string(tt.raw)})
+			err := newValueSetter(i.Type(), codecConfig{})(i, rawValue{data: string(tt.raw)})
 			if tt.shouldErr != (err != nil) {
 				t.Errorf("newValueSetter(%s)() err want %v, have %v (%v)", reflect.TypeOf(tt.expected).Name(), tt.shouldErr, err != nil, err.Error())
 			}
@@ -337,6 +344,50 @@ func TestNewValueSetter(t *testing.T) {
 	}
 }
 
+func TestDecoder_SetSaturating(t *testing.T) {
+	type H struct {
+		Int8   int8   `fixed:"1,4"`
+		Uint8  uint8  `fixed:"5,8"`
+		Packed int16  `fixed:"9,11,comp3"`
+		Zoned  uint16 `fixed:"12,17,overpunch"`
+	}
+	// 99999 does not fit an int16 and -65535 does not fit a uint16.
+	data := append([]byte("-300 256"), 0x99, 0x99, 0x9C)
+	data = append(data, "06553O"...)
+
+	var have H
+	err := NewDecoder(bytes.NewReader(data)).Decode(&have)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "Int8" {
+		t.Errorf("Decode() want *UnmarshalTypeError for Int8, have %v", err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.SetSaturating(true)
+	if err := dec.Decode(&have); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if want := (H{-128, 255, 32767, 0}); have != want {
+		t.Errorf("Decode() want %+v, have %+v", want, have)
+	}
+
+	t.Run("top level", func(t *testing.T) {
+		// Values that are not structs report the same error as struct fields.
+		var i int8
+		err := Unmarshal([]byte("-300"), &i)
+		var typeErr *UnmarshalTypeError
+		var numErr *strconv.NumError
+		if !errors.As(err, &typeErr) || typeErr.Type != reflect.TypeOf(i) || !errors.As(err, &numErr) {
+			t.Errorf("Unmarshal() want *UnmarshalTypeError, have %v", err)
+		}
+
+		var s []uint8
+		if err := Unmarshal([]byte("1\n256"), &s); !errors.As(err, &typeErr) || typeErr.Value != "256" {
+			t.Errorf("Unmarshal() want *UnmarshalTypeError, have %v", err)
+		}
+	})
+}
+
 func TestDecodeSetUseCodepointIndices(t *testing.T) {
 	type S struct {
 		A string `fixed:"1,5"`
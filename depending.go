@@ -0,0 +1,183 @@
+package fixedwidth
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// dependingSpec describes a variable-count repeating group (COBOL OCCURS DEPENDING
+// ON): a slice field whose number of elements is held by a count field that precedes
+// it. The tag of the group describes its largest size. The fields after the group
+// move towards it by the width of the missing elements, so their positions are
+// relative to the end of the group.
+type dependingSpec struct {
+	group *groupSpec
+	c     codecConfig
+
+	field     string
+	index     int // index of the count field
+	countType reflect.Type
+}
+
+// newDependingSpec returns the dependingSpec of the field sf of the struct t with the
+// given options. spec is the spec of the group at its largest size.
+func newDependingSpec(t reflect.Type, sf reflect.StructField, spec *fieldSpec, opts fieldOptions, c codecConfig) (*dependingSpec, error) {
+	if sf.Type.Kind() != reflect.Slice {
+		return nil, errors.New("fixedwidth: depending option on non-slice field of type " + sf.Type.String())
+	}
+	if opts.occurs == 0 {
+		return nil, errors.New("fixedwidth: depending option without occurs option")
+	}
+	cf, ok := t.FieldByName(opts.depending)
+	if !ok || len(cf.Index) != 1 {
+		return nil, errors.New("fixedwidth: count field " + opts.depending + " not found")
+	}
+	switch indirectType(cf.Type).Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+		reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+	default:
+		return nil, errors.New("fixedwidth: count field " + opts.depending + " is not an integer")
+	}
+
+	g, err := newGroupSpec(sf.Type, spec, opts, c)
+	if err != nil {
+		return nil, err
+	}
+	return &dependingSpec{group: g, c: c, field: opts.depending, index: cf.Index[0], countType: cf.Type}, nil
+}
+
+// count returns the value of the count field v. Values that do not fit an int32 are
+// clamped, as they are out of range of any group.
+func (d *dependingSpec) count(v reflect.Value) int {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0
+		}
+		v = v.Elem()
+	}
+	var n int64
+	if v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64 {
+		n = math.MaxInt32
+		if v.Uint() < math.MaxInt32 {
+			n = int64(v.Uint())
+		}
+	} else {
+		n = v.Int()
+	}
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if n < math.MinInt32 {
+		return math.MinInt32
+	}
+	return int(n)
+}
+
+// countEncoder returns an encoder of the count field that encodes n with enc, the
+// encoder of the field, whatever the value of the field.
+func (d *dependingSpec) countEncoder(enc valueEncoder, n int) valueEncoder {
+	return func(reflect.Value) (rawValue, error) {
+		v := reflect.New(d.countType).Elem()
+		e := v
+		if e.Kind() == reflect.Ptr {
+			e.Set(reflect.New(e.Type().Elem()))
+			e = e.Elem()
+		}
+		if e.Kind() >= reflect.Uint && e.Kind() <= reflect.Uint64 {
+			e.SetUint(uint64(n))
+		} else {
+			e.SetInt(int64(n))
+		}
+		return enc(v)
+	}
+}
+
+// dependingGroups returns the indices of the variable-count groups of ss ordered by
+// position.
+func (ss structSpec) dependingGroups() []int {
+	var groups []int
+	for i, spec := range ss.fieldSpecs {
+		if spec.depending != nil {
+			groups = append(groups, i)
+		}
+	}
+	sort.SliceStable(groups, func(a, b int) bool {
+		return ss.fieldSpecs[groups[a]].startPos < ss.fieldSpecs[groups[b]].startPos
+	})
+	return groups
+}
+
+// forLine returns the spec of a line of ss whose variable-count groups have the
+// number of elements returned by count, given the spec of the group and of its count
+// field at their positions within the line. The positions of the fields that follow
+// a group are moved towards it by the width of its missing elements, the count fields
+// are encoded as the number of elements, and the line length is adjusted accordingly.
+func (ss structSpec) forLine(count func(i int, d *dependingSpec, countSpec fieldSpec) (int, error)) (structSpec, error) {
+	if len(ss.groups) == 0 {
+		return ss, nil
+	}
+
+	// gaps holds the end position and the width of the missing elements of each
+	// group handled so far.
+	type gap struct{ end, width int }
+	var gaps []gap
+	shift := func(pos int) int {
+		n := 0
+		for _, g := range gaps {
+			if g.end < pos {
+				n += g.width
+			}
+		}
+		return n
+	}
+
+	line := ss
+	line.fieldSpecs = append([]fieldSpec(nil), ss.fieldSpecs...)
+	counts := make(map[int]int, len(ss.groups))
+	for _, i := range ss.groups {
+		d := ss.fieldSpecs[i].depending
+		cs := ss.fieldSpecs[d.index]
+		if !cs.ok || cs.endPos >= ss.fieldSpecs[i].startPos {
+			return structSpec{}, errors.New("fixedwidth: count field " + d.field + " must precede its group")
+		}
+		s := shift(cs.startPos)
+		cs.startPos, cs.endPos = cs.startPos-s, cs.endPos-s
+
+		n, err := count(i, d, cs)
+		if err != nil {
+			return structSpec{}, err
+		}
+		if n < 0 || n > d.group.count {
+			return structSpec{}, errors.New("fixedwidth: count of " + strconv.Itoa(n) + " in field " + d.field +
+				" is out of range 0 to " + strconv.Itoa(d.group.count))
+		}
+		counts[i] = n
+		gaps = append(gaps, gap{end: ss.fieldSpecs[i].endPos, width: (d.group.count - n) * d.group.width()})
+		line.fieldSpecs[d.index].encoder = d.countEncoder(line.fieldSpecs[d.index].encoder, n)
+	}
+
+	line.ll = 0
+	for i := range line.fieldSpecs {
+		spec := &line.fieldSpecs[i]
+		if !spec.ok {
+			continue
+		}
+		s := shift(spec.startPos)
+		spec.startPos, spec.endPos = spec.startPos-s, spec.endPos-s
+		if d := spec.depending; d != nil {
+			n := counts[i]
+			spec.endPos = spec.startPos + n*d.group.width() - 1
+			spec.encoder = d.group.encoderOf(n, d.c)
+			spec.setter = func(v reflect.Value, raw rawValue) error {
+				return d.group.decode(v, raw, n)
+			}
+		}
+		if spec.endPos > line.ll {
+			line.ll = spec.endPos
+		}
+	}
+	return line, nil
+}
@@ -0,0 +1,130 @@
+package fixedwidth
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDepending(t *testing.T) {
+	type Segment struct {
+		Code   string `fixed:"1,2"`
+		Amount int    `fixed:"3,5,right,0"`
+	}
+	type H struct {
+		ID       string    `fixed:"1,3"`
+		Count    int       `fixed:"4,4"`
+		Segments []Segment `fixed:"5,19,occurs=3,depending=Count"`
+		Notes    *uint8    `fixed:"20,20"`
+		Lines    []string  `fixed:"21,26,occurs=3,depending=Notes"`
+		Trailer  string    `fixed:"27,29"`
+	}
+
+	for _, tt := range []struct {
+		name string
+		data string
+		want H
+	}{
+		{
+			name: "full",
+			data: "ABC3AA001BB002CC0033a b c END",
+			want: H{
+				ID:       "ABC",
+				Count:    3,
+				Segments: []Segment{{"AA", 1}, {"BB", 2}, {"CC", 3}},
+				Notes:    ptrUint8(3),
+				Lines:    []string{"a", "b", "c"},
+				Trailer:  "END",
+			},
+		},
+		{
+			name: "partial",
+			data: "ABC1AA0011a END",
+			want: H{
+				ID:       "ABC",
+				Count:    1,
+				Segments: []Segment{{"AA", 1}},
+				Notes:    ptrUint8(1),
+				Lines:    []string{"a"},
+				Trailer:  "END",
+			},
+		},
+		{
+			name: "empty",
+			data: "ABC00END",
+			want: H{ID: "ABC", Segments: []Segment{}, Notes: ptrUint8(0), Lines: []string{}, Trailer: "END"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var have H
+			if err := Unmarshal([]byte(tt.data), &have); err != nil {
+				t.Fatalf("Unmarshal(%q) unexpected error: %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("Unmarshal(%q) want %+v, have %+v", tt.data, tt.want, have)
+			}
+
+			o, err := Marshal(tt.want)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+			if string(o) != tt.data {
+				t.Errorf("Marshal() want %q, have %q", tt.data, o)
+			}
+		})
+	}
+
+	t.Run("count updated", func(t *testing.T) {
+		v := H{ID: "ABC", Count: 9, Segments: []Segment{{"AA", 1}, {"BB", 2}}, Trailer: "END"}
+		o, err := Marshal(v)
+		if want := "ABC2AA001BB0020END"; err != nil || string(o) != want {
+			t.Errorf("Marshal() want %q, have %q, %v", want, o, err)
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		var h H
+		if err := Unmarshal([]byte("ABC4AA001BB002CC003DD0040END"), &h); err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Errorf("Unmarshal() want out of range error, have %v", err)
+		}
+		_, err := Marshal(H{Lines: []string{"a", "b", "c", "d"}})
+		if err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Errorf("Marshal() want out of range error, have %v", err)
+		}
+	})
+}
+
+func TestDepending_invalid(t *testing.T) {
+	for _, v := range []interface{}{
+		&struct {
+			A []int `fixed:"1,6,occurs=3,depending=N"`
+		}{},
+		&struct {
+			N string `fixed:"1,1"`
+			A []int  `fixed:"2,7,occurs=3,depending=N"`
+		}{},
+		&struct {
+			N int    `fixed:"1,1"`
+			A [3]int `fixed:"2,7,occurs=3,depending=N"`
+		}{},
+		&struct {
+			N int   `fixed:"1,1"`
+			A []int `fixed:"2,7,depending=N"`
+		}{},
+		&struct {
+			A []int `fixed:"1,6,occurs=3,depending=N"`
+			N int   `fixed:"7,7"`
+		}{},
+	} {
+		if err := Unmarshal([]byte("1234567"), v); err == nil {
+			t.Errorf("Unmarshal(%T) want error", v)
+		}
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("Marshal(%T) want error", v)
+		}
+	}
+}
+
+func ptrUint8(v uint8) *uint8 {
+	return &v
+}
@@ -0,0 +1,159 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// A signStyle describes how the sign of a display decimal or formatted number is
+// written.
+type signStyle int
+
+const (
+	signMinus         signStyle = iota // '-' before negative numbers
+	signPlus                           // '+' or '-' before the number
+	signTrailingMinus                  // '-' after negative numbers
+	signLeading                        // '+' or '-' at the start of the field
+	signTrailing                       // '+' or '-' at the end of the field
+)
+
+// signStyles maps the values of the sign tag option to sign styles.
+var signStyles = map[string]signStyle{
+	"minus":          signMinus,
+	"plus":           signPlus,
+	"trailing-minus": signTrailingMinus,
+	"leading":        signLeading,
+	"trailing":       signTrailing,
+}
+
+// separate reports whether the sign is written at the edge of the field rather than
+// next to the digits.
+func (s signStyle) separate() bool {
+	return s == signLeading || s == signTrailing
+}
+
+// affixes returns the characters written before and after the digits of a number.
+func (s signStyle) affixes(negative bool) (prefix, suffix string) {
+	sign := "+"
+	if negative {
+		sign = "-"
+	}
+	switch {
+	case s == signPlus, s == signLeading:
+		return sign, ""
+	case s == signTrailing, s == signTrailingMinus && negative:
+		return "", sign
+	case s == signMinus && negative:
+		return sign, ""
+	}
+	return "", ""
+}
+
+// parseSigned splits a number into its digits and sign. The sign may precede or
+// follow the digits, and spaces around either are ignored, so that every sign style
+// is accepted.
+func parseSigned(b []byte) (digits []byte, negative, ok bool) {
+	b = bytes.Trim(b, " ")
+	if len(b) == 0 {
+		return nil, false, false
+	}
+	var sign byte
+	switch last := b[len(b)-1]; {
+	case b[0] == '+' || b[0] == '-':
+		sign, b = b[0], b[1:]
+	case last == '+' || last == '-':
+		sign, b = last, b[:len(b)-1]
+	}
+	b = bytes.Trim(b, " ")
+	return b, sign == '-', len(b) > 0 && b[0] != '+' && b[0] != '-'
+}
+
+// displayStyle describes how a display decimal field is encoded. Display decimals
+// store the digits of the coefficient as text with an implied decimal point, e.g.
+// "000000003042" for 30.42 with scale=2, padded with zeros to the width of the field.
+type displayStyle struct {
+	sign signStyle
+}
+
+// parse decodes a display decimal with the given scale. A field of spaces is blank.
+func (s displayStyle) parse(b []byte, scale int) (d Decimal, ok bool, err error) {
+	if isBlank(b) {
+		return Decimal{}, false, nil
+	}
+	digits, negative, ok := parseSigned(b)
+	if !ok {
+		return Decimal{}, false, &strconv.NumError{Func: "parseDisplay", Num: string(b), Err: strconv.ErrSyntax}
+	}
+
+	var c int64
+	for _, x := range digits {
+		if x < '0' || x > '9' {
+			return Decimal{}, false, &strconv.NumError{Func: "parseDisplay", Num: string(b), Err: strconv.ErrSyntax}
+		}
+		digit := int64(x - '0')
+		if c > (math.MaxInt64-digit)/10 {
+			return Decimal{}, false, errDecimalRange
+		}
+		c = c*10 + digit
+	}
+	if negative {
+		c = -c
+	}
+	return Decimal{Coefficient: c, Scale: scale}, true, nil
+}
+
+// format encodes the coefficient of d as a display decimal of width characters.
+func (s displayStyle) format(d Decimal, width int) ([]byte, error) {
+	c := d.Coefficient
+	prefix, suffix := s.sign.affixes(c < 0)
+	digits := width - len(prefix) - len(suffix)
+	if n := countDigits(c); n > digits {
+		// The sign is part of the length of the value.
+		return nil, &OverflowError{Width: width, Len: n + width - digits}
+	}
+
+	b := make([]byte, width)
+	copy(b, prefix)
+	copy(b[width-len(suffix):], suffix)
+	for i := width - len(suffix) - 1; i >= len(prefix); i-- {
+		digit := c % 10
+		if digit < 0 {
+			digit = -digit
+		}
+		c /= 10
+		b[i] = '0' + byte(digit)
+	}
+	return b, nil
+}
+
+// displaySetter decodes a display decimal field with the given scale.
+func displaySetter(s displayStyle, scale int, c codecConfig) valueSetter {
+	return numericSetter(func(b []byte) (Decimal, bool, error) {
+		return s.parse(b, scale)
+	}, displayConfig(c))
+}
+
+// displayEncoder encodes a display decimal field of width characters with the given
+// scale.
+func displayEncoder(s displayStyle, width, scale int, mode roundingMode, c codecConfig) valueEncoder {
+	return numericEncoder(scale, mode, func(d Decimal) ([]byte, error) {
+		return s.format(d, width)
+	}, displayConfig(c))
+}
+
+// displayConfig returns the config used for the bytes of a display decimal field.
+// Display decimals are text, so they are translated with the rest of a translated
+// stream.
+func displayConfig(c codecConfig) codecConfig {
+	c.codePage = nil
+	return c
+}
+
+// isDisplayDecimal reports whether a field of type t with the given options is a
+// display decimal.
+// A separate sign makes a field without a scale or precision a display decimal.
+func isDisplayDecimal(t reflect.Type, opts fieldOptions) bool {
+	return (opts.hasScale || opts.sign.separate() && !opts.hasPrec) && isNumericKind(t)
+}
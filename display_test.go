@@ -0,0 +1,79 @@
+package fixedwidth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDisplay(t *testing.T) {
+	type H struct {
+		Float    float64  `fixed:"1,12,scale=2"`
+		Minor    int64    `fixed:"13,18,scale=2"`
+		Exact    Decimal  `fixed:"19,24,scale=3"`
+		Negative int      `fixed:"25,29,scale=0"`
+		Leading  Decimal  `fixed:"30,35,scale=2,sign=leading"`
+		Trailing int      `fixed:"36,40,sign=trailing"`
+		Unsigned uint     `fixed:"41,44,scale=1"`
+		Blank    *float64 `fixed:"45,48,scale=2"`
+	}
+
+	data := "000000003042" + "004001" + "012345" + "-0042" + "-00150" + "0007+" + "0123" + "    "
+	want := H{30.42, 4001, Decimal{12345, 3}, -42, Decimal{-150, 2}, 7, 123, nil}
+
+	var have H
+	if err := Unmarshal([]byte(data), &have); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if have != want {
+		t.Errorf("Unmarshal() want %+v, have %+v", want, have)
+	}
+
+	o, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if string(o) != data {
+		t.Errorf("Marshal() want %q, have %q", data, o)
+	}
+
+	t.Run("lenient decoding", func(t *testing.T) {
+		var have H
+		data := "       30.42" + "  4001" + "+12345" + "  -42" + " 00150" + "   7 "
+		if err := Unmarshal([]byte(data), &have); err == nil {
+			t.Errorf("Unmarshal() want error for a decimal point")
+		}
+		data = "        3042" + "  4001" + "+12345" + "  -42" + " 00150" + "   7 "
+		want := H{Float: 30.42, Minor: 4001, Exact: Decimal{12345, 3}, Negative: -42, Leading: Decimal{150, 2}, Trailing: 7}
+		if err := Unmarshal([]byte(data), &have); err != nil || have != want {
+			t.Errorf("Unmarshal() want %+v, have %+v, %v", want, have, err)
+		}
+	})
+}
+
+func TestDisplay_errors(t *testing.T) {
+	type H struct {
+		F1 int `fixed:"1,3,scale=1"`
+		F2 int `fixed:"4,6,sign=leading"`
+	}
+
+	for _, data := range []string{"1X3000", "123*00", "123+", "1-2000"} {
+		var v H
+		err := Unmarshal([]byte(data), &v)
+		var typeErr *UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			t.Errorf("Unmarshal(%q) want *UnmarshalTypeError, have %v", data, err)
+		}
+	}
+
+	for _, v := range []H{{F1: 1234}, {F1: -100}, {F2: 100}} {
+		_, err := Marshal(v)
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) {
+			t.Errorf("Marshal(%+v) want *OverflowError, have %v", v, err)
+		}
+	}
+
+	if _, _, _, _, ok := parseTagWithOptions("1,3,sign=both"); ok {
+		t.Errorf("parseTagWithOptions() want invalid sign")
+	}
+}
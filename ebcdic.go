@@ -1,16 +1,19 @@
 package fixedwidth
 
 import (
-	"github.com/indece-official/go-ebcdic"
+	"reflect"
 )
 
+// EbcdicString is a string that is encoded in EBCDIC code page 037. A different
+// code page can be chosen per field with the codepage tag option, e.g.
+// `fixed:"1,10,codepage=500"`.
 type EbcdicString struct {
 	S string
 }
 
 // UnmarshalText implements encoding.TextUnmarshaler.
 func (s *EbcdicString) UnmarshalText(text []byte) error {
-	decoded, err := ebcdic.Decode(text, ebcdic.EBCDIC037)
+	decoded, err := CodePage037.Decode(text)
 	if err != nil {
 		return err
 	}
@@ -20,7 +23,7 @@ func (s *EbcdicString) UnmarshalText(text []byte) error {
 
 // MarshalText implements encoding.TextUnmarshaler.
 func (s EbcdicString) MarshalText() ([]byte, error) {
-	encoded, err := ebcdic.Encode(s.S, ebcdic.EBCDIC037)
+	encoded, err := CodePage037.Encode(s.S)
 	if err != nil {
 		return nil, err
 	}
@@ -30,3 +33,39 @@ func (s EbcdicString) MarshalText() ([]byte, error) {
 func (s EbcdicString) String() string {
 	return s.S
 }
+
+var ebcdicStringType = reflect.TypeOf(EbcdicString{})
+
+func isEbcdicString(t reflect.Type) bool {
+	return t == ebcdicStringType || (t.Kind() == reflect.Ptr && t.Elem() == ebcdicStringType)
+}
+
+// ebcdicTextSetter sets an EbcdicString, or a pointer to one, from text that has
+// already been decoded.
+func ebcdicTextSetter(t reflect.Type) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		if t.Kind() == reflect.Ptr {
+			if len(raw.data) == 0 {
+				return nilSetter(v, raw)
+			}
+			if v.IsNil() {
+				v.Set(reflect.New(ebcdicStringType))
+			}
+			v = v.Elem()
+		}
+		v.Field(0).SetString(raw.data)
+		return nil
+	}
+}
+
+// ebcdicTextEncoder returns the text of an EbcdicString, or a pointer to one, without
+// encoding it.
+func ebcdicTextEncoder(v reflect.Value) (rawValue, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nilEncoder(v)
+		}
+		v = v.Elem()
+	}
+	return rawValue{data: v.Field(0).String()}, nil
+}
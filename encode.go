@@ -7,7 +7,6 @@ import (
 	"io"
 	"reflect"
 	"strconv"
-	"strings"
 )
 
 // Marshal returns the fixed-width encoding of v.
@@ -28,6 +27,10 @@ import (
 // nil pointers and interfaces will be omitted. zero vales
 // will be encoded normally.
 //
+// A composite struct, a struct with fields tagged with
+// the record option, is encoded to one line per non-nil
+// field. See Decoder.Decode for details.
+//
 // A struct is encoded to a single slice of bytes. Each
 // field in a struct will be encoded and placed at the
 // position defined by its struct tags. The tags should be
@@ -37,7 +40,8 @@ import (
 //
 // If the encoded value of a field is longer than the
 // length of the position interval, the overflow is
-// truncated.
+// truncated. Use MarshalStrict or Encoder.SetStrict to
+// report an *OverflowError instead.
 func Marshal(v interface{}) ([]byte, error) {
 	buff := bytes.NewBuffer(nil)
 	err := NewEncoder(buff).Encode(v)
@@ -47,6 +51,23 @@ func Marshal(v interface{}) ([]byte, error) {
 	return buff.Bytes(), nil
 }
 
+// MarshalStrict is like Marshal but returns an *OverflowError
+// instead of truncating a value that is longer than the
+// length of its position interval.
+//
+// Fields tagged with the truncate option, e.g.
+// `fixed:"1,5,truncate"`, are still truncated.
+func MarshalStrict(v interface{}) ([]byte, error) {
+	buff := bytes.NewBuffer(nil)
+	enc := NewEncoder(buff)
+	enc.SetStrict(true)
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
 // MarshalInvalidTypeError describes an invalid type being marshaled.
 type MarshalInvalidTypeError struct {
 	typeName string
@@ -56,13 +77,46 @@ func (e *MarshalInvalidTypeError) Error() string {
 	return "fixedwidth: cannot marshal unknown Type " + e.typeName
 }
 
+// An OverflowError describes a value that is longer than the
+// position interval of the struct field it is encoded into.
+// Plain text, integer and float fields report it in strict mode
+// only and are truncated otherwise. Display decimals (the scale
+// or sign options), formatted numbers (the sign, prec or round
+// options), packed, zoned and binary fields, repeating groups
+// (occurs), and framed records always report it, as a truncated
+// number or record would be silently wrong.
+type OverflowError struct {
+	Struct string // name of the struct type containing the field
+	Field  string // name of the field holding the Go value
+	Width  int    // width of the field's position interval
+	Len    int    // length of the encoded value
+}
+
+func (e *OverflowError) Error() string {
+	return "fixedwidth: value of length " + strconv.Itoa(e.Len) +
+		" overflows Go struct field " + e.Struct + "." + e.Field +
+		" of width " + strconv.Itoa(e.Width)
+}
+
 // An Encoder writes fixed-width formatted data to an output
 // stream.
 type Encoder struct {
 	w              *bufio.Writer
 	lineTerminator []byte
 
-	useCodepointIndices bool
+	// recordLength is the length of records when they are framed by length rather
+	// than by line terminators.
+	recordLength int
+
+	// recordFormat is the framing of records. block holds the records of the current
+	// block of RecordFormatVB, which is at most blockSize bytes, and lengthPrefix is
+	// the header of records of RecordFormatLengthPrefix.
+	recordFormat RecordFormat
+	blockSize    int
+	block        []byte
+	lengthPrefix LengthPrefix
+
+	config codecConfig
 
 	lastType         reflect.Type
 	lastValueEncoder valueEncoder
@@ -87,7 +141,35 @@ func (e *Encoder) SetLineTerminator(lineTerminator []byte) {
 // `fixedwidth` struct tags are expressed in terms of bytes (the default
 // behavior) or in terms of UTF-8 decoded codepoints.
 func (e *Encoder) SetUseCodepointIndices(use bool) {
-	e.useCodepointIndices = use
+	e.config.useCodepointIndices = use
+	e.lastType = nil
+}
+
+// SetStrict configures `Encoder` on whether a value that is longer than the
+// position interval of its field is truncated (the default behavior) or
+// reported as an *OverflowError.
+//
+// Truncation can be allowed for individual fields in strict mode with the
+// truncate tag option, e.g. `fixed:"1,5,truncate"`.
+func (e *Encoder) SetStrict(strict bool) {
+	e.config.strict = strict
+	e.lastType = nil
+}
+
+// SetCodePage configures `Encoder` to write the whole output as text encoded in
+// cp, e.g. CodePage037. Lines are built as text, so padding characters are given
+// as text and the indices in the `fixedwidth` struct tags are expressed in terms of
+// characters (bytes of the output).
+//
+// SetCodePage also sets the line terminator to the EBCDIC NL character (0x15). Use
+// SetLineTerminator afterwards to choose a different one, e.g. []byte{0x25}.
+//
+// EbcdicString fields are written as text and translated with the rest of the
+// line. Fields with a codepage tag option are encoded in their own code page instead.
+func (e *Encoder) SetCodePage(cp *CodePage) {
+	e.config.codePage = cp
+	e.lineTerminator = []byte{ebcdicNewLine}
+	e.lastType = nil
 }
 
 // Encode writes the fixed-width encoding of v to the
@@ -125,8 +207,7 @@ func (e *Encoder) writeLines(v reflect.Value) error {
 		}
 
 		if i != v.Len()-1 {
-			_, err := e.w.Write(e.lineTerminator)
-			if err != nil {
+			if err := e.writeLineTerminator(); err != nil {
 				return err
 			}
 		}
@@ -135,11 +216,15 @@ func (e *Encoder) writeLines(v reflect.Value) error {
 }
 
 func (e *Encoder) writeLine(v reflect.Value) (err error) {
+	if records, ok := e.compositeRecords(v); ok {
+		return e.writeComposite(records)
+	}
+
 	t := v.Type()
 	encoder := e.lastValueEncoder
 	if e.lastType != t {
 		e.lastType = t
-		e.lastValueEncoder = newValueEncoder(t, e.useCodepointIndices)
+		e.lastValueEncoder = newValueEncoder(t, e.config)
 		encoder = e.lastValueEncoder
 	}
 
@@ -147,27 +232,48 @@ func (e *Encoder) writeLine(v reflect.Value) (err error) {
 	if err != nil {
 		return err
 	}
-	_, err = e.w.WriteString(b.data)
-	return err
+	if e.config.codePage == nil && e.recordLength == 0 && e.recordFormat == RecordFormatLine {
+		_, err = e.w.WriteString(b.data)
+		return err
+	}
+
+	record := []byte(b.data)
+	if cp := e.config.codePage; cp != nil {
+		if record, err = cp.Encode(b.data); err != nil {
+			return err
+		}
+	}
+	return e.writeRecord(record)
 }
 
 type valueEncoder func(v reflect.Value) (rawValue, error)
 
-func newValueEncoder(t reflect.Type, useCodepointIndices bool) valueEncoder {
+func newValueEncoder(t reflect.Type, c codecConfig) valueEncoder {
 	if t == nil {
 		return nilEncoder
 	}
+	if t == recordType {
+		return recordEncoder(c)
+	}
+	if c.codePage != nil {
+		// A translated stream is built as text, which may contain multibyte
+		// characters, before it is encoded.
+		c.useCodepointIndices = true
+		if t == ebcdicStringType {
+			return ebcdicTextEncoder
+		}
+	}
 	if t.Implements(reflect.TypeOf(new(encoding.TextMarshaler)).Elem()) {
-		return textMarshalerEncoder(useCodepointIndices)
+		return textMarshalerEncoder(c.useCodepointIndices)
 	}
 
 	switch t.Kind() {
 	case reflect.Ptr, reflect.Interface:
-		return ptrInterfaceEncoder(useCodepointIndices)
+		return ptrInterfaceEncoder(c)
 	case reflect.Struct:
-		return structEncoder(useCodepointIndices)
+		return structEncoder(c)
 	case reflect.String:
-		return stringEncoder(useCodepointIndices)
+		return stringEncoder(c.useCodepointIndices)
 	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
 		return intEncoder
 	case reflect.Float64:
@@ -178,6 +284,10 @@ func newValueEncoder(t reflect.Type, useCodepointIndices bool) valueEncoder {
 		return uintEncoder
 	case reflect.Bool:
 		return boolEncoder
+	case reflect.Slice, reflect.Array:
+		if isBytes(t) {
+			return bytesEncoder(bytesRaw, 0, c)
+		}
 	}
 	return unknownTypeEncoder(t)
 }
@@ -193,7 +303,7 @@ func (ve valueEncoder) Write(b *lineBuilder, v reflect.Value, spec fieldSpec) er
 	if value.len() < spec.len() {
 		switch {
 		case spec.format.alignment == right:
-			padding := strings.Repeat(string(format.padChar), spec.len()-value.len())
+			padding := string(bytes.Repeat([]byte{format.padChar}, spec.len()-value.len()))
 			b.WriteASCII(startIndex, padding)
 			b.WriteValue(startIndex+len(padding), value)
 			return nil
@@ -203,7 +313,7 @@ func (ve valueEncoder) Write(b *lineBuilder, v reflect.Value, spec fieldSpec) er
 		// written to dst. This means overlapping intervals can, in effect, be used to
 		// coalesce a value.
 		case format.alignment == left, format.alignment == defaultAlignment && format.padChar != ' ':
-			padding := strings.Repeat(string(format.padChar), spec.len()-value.len())
+			padding := string(bytes.Repeat([]byte{format.padChar}, spec.len()-value.len()))
 
 			b.WriteValue(startIndex, value)
 			b.WriteASCII(startIndex+value.len(), padding)
@@ -212,8 +322,10 @@ func (ve valueEncoder) Write(b *lineBuilder, v reflect.Value, spec fieldSpec) er
 	}
 
 	if value.len() > spec.len() {
+		if !spec.truncate {
+			return &OverflowError{Width: spec.len(), Len: value.len()}
+		}
 		// If the value is too long it needs to be trimmed.
-		// TODO: Add strict mode that returns in this case.
 		value, err = value.slice(0, spec.len()-1)
 		if err != nil {
 			return err
@@ -224,24 +336,52 @@ func (ve valueEncoder) Write(b *lineBuilder, v reflect.Value, spec fieldSpec) er
 	return nil
 }
 
-func structEncoder(useCodepointIndices bool) valueEncoder {
+func structEncoder(c codecConfig) valueEncoder {
 	return func(v reflect.Value) (rawValue, error) {
-		ss := cachedStructSpec(v.Type())
+		t := v.Type()
+		ss := cachedStructSpec(t, c)
+		if ss.err != nil {
+			return rawValue{}, ss.err
+		}
+		ss, err := ss.forLine(func(i int, _ *dependingSpec, _ fieldSpec) (int, error) {
+			return v.Field(i).Len(), nil
+		})
+		if err != nil {
+			return rawValue{}, err
+		}
+		if ss, err = ss.withCurrencies(v); err != nil {
+			return rawValue{}, err
+		}
 
 		// Add a 10% headroom to the builder when codepoint indices are being used.
-		c := ss.ll
-		if useCodepointIndices {
-			c = int(1.1*float64(ss.ll)) + 1
+		capacity := ss.ll
+		if c.useCodepointIndices {
+			capacity = int(1.1*float64(ss.ll)) + 1
 		}
-		b := newLineBuilder(ss.ll, c, ' ')
+		b := newLineBuilder(ss.ll, capacity, ' ')
 
+		var written []fieldSpec // the alternatives that have been written
 		for i, spec := range ss.fieldSpecs {
 			if !spec.ok {
 				continue
 			}
+			if spec.when != nil {
+				// Only the alternatives that are set are written.
+				if v.Field(i).IsZero() {
+					continue
+				}
+				for _, w := range written {
+					if w.overlaps(spec) {
+						return rawValue{}, conflictingAlternativesError(t, w.when, spec.when)
+					}
+				}
+				written = append(written, spec)
+			}
 
-			enc := spec.getEncoder(useCodepointIndices)
-			err := enc.Write(b, v.Field(i), spec)
+			err := spec.encoder.Write(b, v.Field(i), spec)
+			if oe, ok := err.(*OverflowError); ok && oe.Field == "" {
+				oe.Struct, oe.Field = t.Name(), t.Field(i).Name
+			}
 			if err != nil {
 				return rawValue{}, err
 			}
@@ -261,12 +401,12 @@ func textMarshalerEncoder(useCodepointIndices bool) valueEncoder {
 	}
 }
 
-func ptrInterfaceEncoder(useCodepointIndices bool) valueEncoder {
+func ptrInterfaceEncoder(c codecConfig) valueEncoder {
 	return func(v reflect.Value) (rawValue, error) {
 		if v.IsNil() {
 			return nilEncoder(v)
 		}
-		return newValueEncoder(v.Elem().Type(), useCodepointIndices)(v.Elem())
+		return newValueEncoder(v.Elem().Type(), c)(v.Elem())
 	}
 }
 
@@ -215,6 +215,50 @@ func TestMarshal_format(t *testing.T) {
 	}
 }
 
+func TestMarshalStrict(t *testing.T) {
+	type Inner struct {
+		F1 string `fixed:"1,3"`
+	}
+	type H struct {
+		F1 string `fixed:"1,5"`
+		F2 string `fixed:"6,8,truncate"`
+		F3 Inner  `fixed:"9,11"`
+	}
+
+	for _, tt := range []struct {
+		name string
+		v    interface{}
+		want []byte
+		err  *OverflowError
+	}{
+		{"fits", H{"12345", "123", Inner{"123"}}, []byte("12345123123"), nil},
+		{"overflow", H{"123456", "123", Inner{"123"}}, nil, &OverflowError{"H", "F1", 5, 6}},
+		{"truncate option", H{"12345", "123456", Inner{"123"}}, []byte("12345123123"), nil},
+		{"nested overflow", H{"12345", "123", Inner{"1234"}}, nil, &OverflowError{"Inner", "F1", 3, 4}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := MarshalStrict(tt.v)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("MarshalStrict() unexpected error: %v", err)
+				}
+				if !bytes.Equal(tt.want, have) {
+					t.Errorf("MarshalStrict() want %q, have %q", string(tt.want), string(have))
+				}
+				return
+			}
+			if !reflect.DeepEqual(tt.err, err) {
+				t.Errorf("MarshalStrict() err want %v, have %v", tt.err, err)
+			}
+
+			// The same value is truncated outside of strict mode.
+			if _, err := Marshal(tt.v); err != nil {
+				t.Errorf("Marshal() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func TestMarshal_backwardCompatibility(t *testing.T) {
 	// Overlapping intervals can, in effect, be used to coalesce a value. This tests
 	// ensures this special does not break.
@@ -318,7 +362,7 @@ func TestNewValueEncoder(t *testing.T) {
 		{"*uint nil", nilUint, []byte(""), false},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			o, err := newValueEncoder(reflect.TypeOf(tt.i), false)(reflect.ValueOf(tt.i))
+			o, err := newValueEncoder(reflect.TypeOf(tt.i), codecConfig{})(reflect.ValueOf(tt.i))
 			if tt.shouldErr != (err != nil) {
 				t.Errorf("newValueEncoder(%s)() shouldErr expected %v, have %v (%v)", reflect.TypeOf(tt.i).Name(), tt.shouldErr, err != nil, err)
 			}
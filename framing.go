@@ -0,0 +1,161 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// RecordLengthAuto can be passed to SetRecordLength to take the length of each
+// record from the type it is decoded from or encoded to, i.e. the largest end
+// position of its fields.
+const RecordLengthAuto = -1
+
+// A TruncatedRecordError describes a final record that is shorter than the record
+// length.
+type TruncatedRecordError struct {
+	Offset int64 // offset of the record within the input
+	Len    int   // length of the record
+	Want   int   // expected length of the record
+}
+
+func (e *TruncatedRecordError) Error() string {
+	return "fixedwidth: truncated record at offset " + strconv.FormatInt(e.Offset, 10) +
+		": have " + strconv.Itoa(e.Len) + " bytes, want " + strconv.Itoa(e.Want)
+}
+
+// SetRecordLength configures `Decoder` to read records of exactly n bytes, with no
+// line terminator between them, instead of lines. This is how mainframe and many
+// bank files are written, and allows records to hold bytes that are equal to a line
+// terminator, e.g. in binary fields.
+//
+// If n is RecordLengthAuto, the length of each record is taken from the type it is
+// decoded into. If n is 0, the input is split into lines again.
+//
+// A *TruncatedRecordError is returned for a final record that is shorter than the
+// record length.
+//
+// SetRecordLength turns off a record format set with SetRecordFormat.
+func (d *Decoder) SetRecordLength(n int) {
+	d.recordLength = n
+	d.frameLength = n
+	d.recordFormat = RecordFormatLine
+}
+
+// scanRecord splits the input into records of d.frameLength bytes.
+func (d *Decoder) scanRecord(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	n := d.frameLength
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if n <= 0 {
+		return 0, nil, errNoRecordLength
+	}
+	if len(data) >= n {
+		return n, data[:n], nil
+	}
+	if atEOF {
+		return 0, nil, &TruncatedRecordError{Offset: d.offset, Len: len(data), Want: n}
+	}
+	// Request more data.
+	return 0, nil, nil
+}
+
+var errNoRecordLength = errors.New("fixedwidth: record length is not set")
+
+// setFrameLength sets the length of the next record to the length of the type of v
+// when the record length is RecordLengthAuto.
+func (d *Decoder) setFrameLength(v reflect.Value) error {
+	if d.recordLength != RecordLengthAuto {
+		return nil
+	}
+	n, err := recordLengthOf(v, d.state.layout, d.config)
+	if err != nil {
+		return err
+	}
+	d.frameLength = n
+	return nil
+}
+
+// recordLengthOf returns the length of a record holding the value v holds or points
+// to. The sub-records of a composite struct must all have the same length. l is the
+// layout of Records that do not have a Layout.
+func recordLengthOf(v reflect.Value, l *Layout, c codecConfig) (int, error) {
+	t := indirectType(v.Type())
+	if t == recordType {
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if r, ok := v.Interface().(Record); ok && r.Layout != nil {
+			l = r.Layout
+		}
+		if l == nil {
+			return 0, errNoLayout
+		}
+		return l.Len(), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return 0, errors.New("fixedwidth: cannot take the record length from " + t.String())
+	}
+
+	ss := cachedStructSpec(t, c)
+	if len(ss.records) == 0 {
+		return ss.ll, nil
+	}
+	n := 0
+	for _, rs := range ss.records {
+		ll := cachedStructSpec(indirectType(t.Field(rs.index).Type), c).ll
+		if n != 0 && ll != n {
+			return 0, errors.New("fixedwidth: cannot take the record length from " + t.String() + ": sub-records have different lengths")
+		}
+		n = ll
+	}
+	return n, nil
+}
+
+// SetRecordLength configures `Encoder` to write records of exactly n bytes, with no
+// line terminator between them, instead of lines. Shorter records are padded with
+// spaces and longer records are reported as an *OverflowError.
+//
+// If n is RecordLengthAuto, records are written at the length of their type. If n
+// is 0, records are written as lines again.
+//
+// SetRecordLength turns off a record format set with SetRecordFormat.
+func (e *Encoder) SetRecordLength(n int) {
+	e.recordLength = n
+	e.recordFormat = RecordFormatLine
+}
+
+// writeRecord frames and writes an encoded record.
+func (e *Encoder) writeRecord(record []byte) error {
+	switch e.recordFormat {
+	case RecordFormatLengthPrefix:
+		return e.writePrefixed(record)
+	case RecordFormatV, RecordFormatVB:
+		return e.writeVariable(record)
+	}
+
+	if n := e.recordLength; n > 0 && len(record) != n {
+		if len(record) > n {
+			return &OverflowError{Width: n, Len: len(record)}
+		}
+		space := []byte{' '}
+		if cp := e.config.codePage; cp != nil {
+			space = []byte{cp.encode[' ']}
+		}
+		record = append(record, bytes.Repeat(space, n-len(record))...)
+	}
+	_, err := e.w.Write(record)
+	return err
+}
+
+// writeLineTerminator writes the line terminator between two records, unless
+// records are framed otherwise.
+func (e *Encoder) writeLineTerminator() error {
+	if e.recordLength != 0 || e.recordFormat != RecordFormatLine {
+		return nil
+	}
+	_, err := e.w.Write(e.lineTerminator)
+	return err
+}
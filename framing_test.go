@@ -0,0 +1,134 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type framingRecord struct {
+	Code   string `fixed:"1,2"`
+	Hash   string `fixed:"3,4,none"`
+	Amount int    `fixed:"5,8"`
+}
+
+func TestDecoder_SetRecordLength(t *testing.T) {
+	// The hash bytes include a line terminator.
+	data := []byte("01\x1c\n004202\n\x850007")
+	want := []framingRecord{
+		{"01", "\x1c\n", 42},
+		{"02", "\n\x85", 7},
+	}
+
+	for _, tt := range []struct {
+		name string
+		n    int
+	}{
+		{"fixed", 8},
+		{"auto", RecordLengthAuto},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(data))
+			dec.SetRecordLength(tt.n)
+			var have []framingRecord
+			if err := dec.Decode(&have); err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("Decode() want %q, have %q", want, have)
+			}
+		})
+	}
+
+	t.Run("VISA TC05", func(t *testing.T) {
+		// The TC05 fixtures are 170 byte records, so they can be read back-to-back.
+		data := bytes.Replace(readVISAFixtures(t), []byte("\n"), nil, 1)
+		dec := NewDecoder(bytes.NewReader(data))
+		dec.SetRecordLength(RecordLengthAuto)
+
+		var tcr0 VISA_TC05_TCR0
+		var tcr1 VISA_TC05_TCR1
+		if err := dec.Decode(&tcr0); err != nil {
+			t.Fatalf("Decode() unexpected error: %v", err)
+		}
+		if err := dec.Decode(&tcr1); err != nil {
+			t.Fatalf("Decode() unexpected error: %v", err)
+		}
+		if !bytes.Equal(tcr0.MessageHashTotal, []byte{28, 133}) || tcr1.TransactionCode != 25 {
+			t.Errorf("Decode() unexpected records %+v, %+v", tcr0, tcr1)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(data[:13]))
+		dec.SetRecordLength(8)
+		var have []framingRecord
+		err := dec.Decode(&have)
+		var truncErr *TruncatedRecordError
+		if !errors.As(err, &truncErr) || truncErr.Offset != 8 || truncErr.Len != 5 || truncErr.Want != 8 {
+			t.Errorf("Decode() want *TruncatedRecordError, have %v", err)
+		}
+	})
+
+	t.Run("auto with interface", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(data))
+		dec.SetRecordLength(RecordLengthAuto)
+		var have []interface{}
+		if err := dec.Decode(&have); err == nil {
+			t.Errorf("Decode() want error")
+		}
+	})
+}
+
+func TestEncoder_SetRecordLength(t *testing.T) {
+	records := []framingRecord{
+		{"01", "\x1c\n", 42},
+		{"02", "\n\x85", 7},
+	}
+
+	for _, tt := range []struct {
+		name string
+		n    int
+		want string
+	}{
+		{"auto", RecordLengthAuto, "01\x1c\n42  02\n\x857   "},
+		{"padded", 10, "01\x1c\n42    02\n\x857     "},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			buff := new(bytes.Buffer)
+			enc := NewEncoder(buff)
+			enc.SetRecordLength(tt.n)
+			if err := enc.Encode(records); err != nil {
+				t.Fatalf("Encode() unexpected error: %v", err)
+			}
+			if buff.String() != tt.want {
+				t.Errorf("Encode() want %q, have %q", tt.want, buff.String())
+			}
+		})
+	}
+
+	t.Run("code page padding", func(t *testing.T) {
+		buff := new(bytes.Buffer)
+		enc := NewEncoder(buff)
+		enc.SetCodePage(CodePage037)
+		enc.SetRecordLength(10)
+		if err := enc.Encode(framingRecord{Code: "01", Hash: "AB"}); err != nil {
+			t.Fatalf("Encode() unexpected error: %v", err)
+		}
+		want := []byte{0xF0, 0xF1, 0xC1, 0xC2, 0xF0, 0x40, 0x40, 0x40, 0x40, 0x40}
+		if !bytes.Equal(buff.Bytes(), want) {
+			t.Errorf("Encode() want %X, have %X", want, buff.Bytes())
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		enc := NewEncoder(new(bytes.Buffer))
+		enc.SetRecordLength(6)
+		err := enc.Encode(records)
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Width != 6 || overflowErr.Len != 8 {
+			t.Errorf("Encode() want *OverflowError, have %v", err)
+		}
+	})
+}
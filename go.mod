@@ -2,4 +2,4 @@ module github.com/ianlopshire/go-fixedwidth
 
 go 1.23.1
 
-require github.com/indece-official/go-ebcdic v1.2.0 // indirect
+require github.com/indece-official/go-ebcdic v1.2.0
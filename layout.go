@@ -0,0 +1,356 @@
+package fixedwidth
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// A FieldType is the data type of a LayoutField.
+type FieldType int
+
+const (
+	// Alphanumeric fields hold text (COBOL PIC X).
+	Alphanumeric FieldType = iota
+
+	// ZonedDecimal fields hold one digit per byte with an overpunched sign (COBOL PIC 9
+	// with USAGE DISPLAY). See the overpunch tag option.
+	ZonedDecimal
+
+	// PackedDecimal fields hold two digits per byte (COBOL COMP-3). See the comp3 tag
+	// option.
+	PackedDecimal
+
+	// BinaryInteger fields hold a big-endian integer (COBOL COMP). See the binary tag
+	// option.
+	BinaryInteger
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case Alphanumeric:
+		return "alphanumeric"
+	case ZonedDecimal:
+		return "zoned"
+	case PackedDecimal:
+		return "packed"
+	case BinaryInteger:
+		return "binary"
+	}
+	return "FieldType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// A LayoutField describes a single field of a Layout.
+type LayoutField struct {
+	Name       string
+	Start, End int // inclusive positions within the line, starting at 1
+	Type       FieldType
+
+	// Scale is the number of implied decimal places of a numeric field.
+	Scale int
+
+	// Signed reports whether a numeric field holds a sign.
+	Signed bool
+
+	// SignLeading reports whether the sign of a ZonedDecimal field is overpunched on
+	// the first digit rather than the last.
+	SignLeading bool
+
+	// SignSeparate reports whether the sign of a signed ZonedDecimal field is a
+	// separate '+' or '-' character rather than overpunched, at the start of the field
+	// if SignLeading and at its end otherwise (COBOL SIGN SEPARATE).
+	SignSeparate bool
+
+	// Redefines is the name of the field or group that this field redefines, if any.
+	// Fields that share positions are decoded independently.
+	Redefines string
+}
+
+// Tag returns the `fixed` struct tag that describes the field.
+func (f LayoutField) Tag() string {
+	tag := strconv.Itoa(f.Start) + "," + strconv.Itoa(f.End)
+	switch f.Type {
+	case ZonedDecimal:
+		switch {
+		case f.Signed && f.SignSeparate && f.SignLeading:
+			tag += ",sign=leading"
+		case f.Signed && f.SignSeparate:
+			tag += ",sign=trailing"
+		case f.SignLeading:
+			tag += ",overpunch=leading"
+		default:
+			tag += ",overpunch"
+		}
+	case PackedDecimal:
+		tag += ",comp3"
+	case BinaryInteger:
+		tag += ",binary"
+	}
+	if f.Scale > 0 && f.Type != Alphanumeric {
+		tag += ",scale=" + strconv.Itoa(f.Scale)
+	}
+	return tag
+}
+
+// A Layout describes the fields of a record at runtime. It allows records to be
+// decoded and encoded without a Go struct, see Record.
+//
+// A Layout must not be modified after it has been used to decode or encode a Record.
+type Layout struct {
+	Name   string
+	Fields []LayoutField
+
+	once     sync.Once
+	typ      reflect.Type
+	overlaps []bool
+	err      error
+}
+
+// Len returns the length of a line holding the record.
+func (l *Layout) Len() int {
+	n := 0
+	for _, f := range l.Fields {
+		if f.End > n {
+			n = f.End
+		}
+	}
+	return n
+}
+
+// Validate reports whether the fields of the layout are well formed.
+func (l *Layout) Validate() error {
+	_, _, err := l.compile()
+	return err
+}
+
+// compile returns the struct type used to decode and encode records of the layout,
+// along with whether each field overlaps another.
+//
+// Text fields are strings. Numeric fields are a *Decimal if signed or an *uint64 that
+// holds the unscaled amount if unsigned, so blank fields can be told apart from zero.
+func (l *Layout) compile() (reflect.Type, []bool, error) {
+	l.once.Do(func() {
+		if len(l.Fields) == 0 {
+			l.err = errors.New("fixedwidth: layout has no fields")
+			return
+		}
+		names := make(map[string]bool, len(l.Fields))
+		sfs := make([]reflect.StructField, len(l.Fields))
+		for i, f := range l.Fields {
+			if err := f.validate(); err != nil {
+				l.err = err
+				return
+			}
+			if names[f.Name] {
+				l.err = errors.New("fixedwidth: duplicate layout field " + strconv.Quote(f.Name))
+				return
+			}
+			names[f.Name] = true
+
+			t := reflect.TypeOf("")
+			switch {
+			case f.Type == Alphanumeric:
+			case f.Signed:
+				t = reflect.TypeOf(new(Decimal))
+			default:
+				t = reflect.TypeOf(new(uint64))
+			}
+			sfs[i] = reflect.StructField{
+				Name: "F" + strconv.Itoa(i),
+				Type: t,
+				Tag:  reflect.StructTag(`fixed:"` + f.Tag() + `"`),
+			}
+		}
+
+		l.overlaps = make([]bool, len(l.Fields))
+		for i, a := range l.Fields {
+			for j, b := range l.Fields {
+				if i != j && a.Start <= b.End && b.Start <= a.End {
+					l.overlaps[i] = true
+				}
+			}
+		}
+		l.typ = reflect.StructOf(sfs)
+	})
+	return l.typ, l.overlaps, l.err
+}
+
+func (f LayoutField) validate() error {
+	switch {
+	case f.Name == "":
+		return errors.New("fixedwidth: layout field has no name")
+	case f.Start < 1 || f.End < f.Start:
+		return fmt.Errorf("fixedwidth: layout field %q has invalid positions %d-%d", f.Name, f.Start, f.End)
+	case f.Type < Alphanumeric || f.Type > BinaryInteger:
+		return fmt.Errorf("fixedwidth: layout field %q has invalid type %v", f.Name, f.Type)
+	case f.Scale < 0:
+		return fmt.Errorf("fixedwidth: layout field %q has negative scale", f.Name)
+	}
+	return nil
+}
+
+// A Record holds the values of a record described by a Layout, keyed by field name.
+//
+// Alphanumeric fields are decoded as strings and numeric fields as Decimals with the
+// scale of the field. Blank numeric fields, and fields that overlap another field and
+// cannot be decoded, are omitted from Values.
+//
+// When encoding, numeric values may be a Decimal, an integer, a float or a decimal
+// string. Fields without a value are left blank.
+type Record struct {
+	Layout *Layout
+	Values map[string]interface{}
+}
+
+// NewRecord returns an empty record with the given layout.
+func NewRecord(l *Layout) *Record {
+	return &Record{Layout: l, Values: make(map[string]interface{})}
+}
+
+var recordType = reflect.TypeOf(Record{})
+
+var errNoLayout = errors.New("fixedwidth: Record has no Layout")
+
+// SetLayout configures `Decoder` to decode lines into Records that do not have a
+// Layout, e.g. the elements of a []Record, with l.
+func (d *Decoder) SetLayout(l *Layout) {
+	d.state.layout = l
+}
+
+func recordSetter(c codecConfig) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		r := v.Addr().Interface().(*Record)
+		if r.Layout == nil && raw.state != nil {
+			r.Layout = raw.state.layout
+		}
+		if r.Layout == nil {
+			return errNoLayout
+		}
+		t, overlaps, err := r.Layout.compile()
+		if err != nil {
+			return err
+		}
+
+		sv := reflect.New(t).Elem()
+		r.Values = make(map[string]interface{}, len(r.Layout.Fields))
+		for i, spec := range cachedStructSpec(t, c).fieldSpecs {
+			f := r.Layout.Fields[i]
+			value := rawValueFromLine(raw, spec.startPos, spec.endPos, spec.format)
+			if err := spec.setter(sv.Field(i), value); err != nil {
+				if overlaps[i] {
+					continue
+				}
+				return &UnmarshalTypeError{raw.data, t.Field(i).Type, r.Layout.Name, f.Name, err}
+			}
+
+			switch fv := sv.Field(i).Interface().(type) {
+			case string:
+				r.Values[f.Name] = fv
+			case *Decimal:
+				if fv != nil {
+					r.Values[f.Name] = *fv
+				}
+			case *uint64:
+				if fv != nil {
+					r.Values[f.Name] = Decimal{Coefficient: int64(*fv), Scale: f.Scale}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func recordEncoder(c codecConfig) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		r := v.Interface().(Record)
+		if r.Layout == nil {
+			return rawValue{}, errNoLayout
+		}
+		t, _, err := r.Layout.compile()
+		if err != nil {
+			return rawValue{}, err
+		}
+
+		ll := r.Layout.Len()
+		capacity := ll
+		if c.useCodepointIndices {
+			capacity = int(1.1*float64(ll)) + 1
+		}
+		b := newLineBuilder(ll, capacity, ' ')
+
+		sv := reflect.New(t).Elem()
+		for i, spec := range cachedStructSpec(t, c).fieldSpecs {
+			f := r.Layout.Fields[i]
+			x, ok := r.Values[f.Name]
+			if !ok || x == nil {
+				continue
+			}
+			if err := setLayoutValue(sv.Field(i), x, f); err != nil {
+				return rawValue{}, err
+			}
+			err := spec.encoder.Write(b, sv.Field(i), spec)
+			if oe, ok := err.(*OverflowError); ok && oe.Field == "" {
+				oe.Struct, oe.Field = r.Layout.Name, f.Name
+			}
+			if err != nil {
+				return rawValue{}, err
+			}
+		}
+		return b.AsRawValue(), nil
+	}
+}
+
+// setLayoutValue stores the value x of the field f in v, a field of the struct type
+// of a layout.
+func setLayoutValue(v reflect.Value, x interface{}, f LayoutField) error {
+	if f.Type == Alphanumeric {
+		s, ok := x.(string)
+		if !ok {
+			return fmt.Errorf("fixedwidth: cannot encode %T as layout field %q", x, f.Name)
+		}
+		v.SetString(s)
+		return nil
+	}
+
+	d, err := layoutDecimal(x)
+	if err != nil {
+		return fmt.Errorf("fixedwidth: cannot encode %T as layout field %q: %w", x, f.Name, err)
+	}
+	if f.Signed {
+		v.Set(reflect.ValueOf(&d))
+		return nil
+	}
+	d, err = d.rescale(f.Scale)
+	if err != nil {
+		return err
+	}
+	if d.Coefficient < 0 {
+		return fmt.Errorf("fixedwidth: cannot encode negative value %v as unsigned layout field %q", d, f.Name)
+	}
+	u := uint64(d.Coefficient)
+	v.Set(reflect.ValueOf(&u))
+	return nil
+}
+
+// layoutDecimal converts a numeric value of a Record to a Decimal.
+func layoutDecimal(x interface{}) (Decimal, error) {
+	switch x := x.(type) {
+	case Decimal:
+		return x, nil
+	case *Decimal:
+		return *x, nil
+	case string:
+		return ParseDecimal(x)
+	case float64:
+		return ParseDecimal(strconv.FormatFloat(x, 'f', -1, 64))
+	case float32:
+		return ParseDecimal(strconv.FormatFloat(float64(x), 'f', -1, 32))
+	}
+	v := reflect.ValueOf(x)
+	if !isNumericKind(v.Type()) || v.Kind() == reflect.Ptr {
+		return Decimal{}, errors.New("not a number")
+	}
+	return decimalOf(v, 0, roundHalfUp)
+}
@@ -0,0 +1,172 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testLayout = &Layout{
+	Name: "Payment",
+	Fields: []LayoutField{
+		{Name: "ID", Start: 1, End: 4, Type: ZonedDecimal},
+		{Name: "Payee", Start: 5, End: 12, Type: Alphanumeric},
+		{Name: "Amount", Start: 13, End: 16, Type: PackedDecimal, Scale: 2, Signed: true},
+		{Name: "Count", Start: 17, End: 18, Type: BinaryInteger},
+	},
+}
+
+func TestLayoutField_Tag(t *testing.T) {
+	for _, tt := range []struct {
+		f    LayoutField
+		want string
+	}{
+		{LayoutField{Start: 1, End: 5}, "1,5"},
+		{LayoutField{Start: 1, End: 5, Type: ZonedDecimal, Scale: 2}, "1,5,overpunch,scale=2"},
+		{LayoutField{Start: 1, End: 5, Type: ZonedDecimal, Signed: true, SignLeading: true}, "1,5,overpunch=leading"},
+		{LayoutField{Start: 1, End: 5, Type: ZonedDecimal, Signed: true, SignLeading: true, SignSeparate: true}, "1,5,sign=leading"},
+		{LayoutField{Start: 1, End: 5, Type: ZonedDecimal, Scale: 2, Signed: true, SignSeparate: true}, "1,5,sign=trailing,scale=2"},
+		{LayoutField{Start: 6, End: 9, Type: PackedDecimal, Scale: 3}, "6,9,comp3,scale=3"},
+		{LayoutField{Start: 6, End: 7, Type: BinaryInteger}, "6,7,binary"},
+	} {
+		if have := tt.f.Tag(); have != tt.want {
+			t.Errorf("Tag() want %q, have %q", tt.want, have)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	data := []byte("0042ACME    \x00\x12\x34\x5D\x01\x00")
+	want := map[string]interface{}{
+		"ID":     Decimal{Coefficient: 42},
+		"Payee":  "ACME",
+		"Amount": Decimal{Coefficient: -12345, Scale: 2},
+		"Count":  Decimal{Coefficient: 256},
+	}
+
+	r := NewRecord(testLayout)
+	if err := Unmarshal(data, r); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(r.Values, want) {
+		t.Errorf("Unmarshal() want %v, have %v", want, r.Values)
+	}
+
+	// Values of other numeric types are converted.
+	r.Values["ID"] = 42
+	r.Values["Amount"] = -123.45
+	r.Values["Count"] = "256"
+	have, err := Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, have) {
+		t.Errorf("Marshal() want %q, have %q", data, have)
+	}
+
+	t.Run("blank fields", func(t *testing.T) {
+		have, err := Marshal(Record{Layout: testLayout, Values: map[string]interface{}{"Payee": "X", "Amount": 0}})
+		if err != nil {
+			t.Fatalf("Marshal() unexpected error: %v", err)
+		}
+		if want := "    X       \x00\x00\x00\x0C"; string(have[:16]) != want {
+			t.Errorf("Marshal() want %q, have %q", want, have)
+		}
+
+		r := NewRecord(testLayout)
+		if err := Unmarshal(have, r); err != nil {
+			t.Fatalf("Unmarshal() unexpected error: %v", err)
+		}
+		if _, ok := r.Values["ID"]; ok {
+			t.Errorf("Unmarshal() want no ID, have %v", r.Values["ID"])
+		}
+	})
+}
+
+func TestRecord_slice(t *testing.T) {
+	data := "0001A       \x00\x00\x00\x0C\x00\x00\n0002B       \x00\x00\x00\x0C\x00\x01"
+	dec := NewDecoder(strings.NewReader(data))
+	dec.SetLayout(testLayout)
+
+	var records []Record
+	if err := dec.Decode(&records); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[1].Values["Payee"] != "B" || records[1].Layout != testLayout {
+		t.Errorf("Decode() unexpected records %+v", records)
+	}
+
+	if err := Unmarshal([]byte(data), &records); !errors.Is(err, errNoLayout) {
+		t.Errorf("Unmarshal() want errNoLayout, have %v", err)
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		// Each layout is decoded with the same cached specs.
+		n := cachedStructSpecs()
+		for i := 0; i < 3; i++ {
+			l := &Layout{Name: testLayout.Name, Fields: testLayout.Fields}
+			dec := NewDecoder(strings.NewReader(data))
+			dec.SetLayout(l)
+			var records []Record
+			if err := dec.Decode(&records); err != nil || records[0].Layout != l {
+				t.Fatalf("Decode() unexpected result %+v, %v", records, err)
+			}
+		}
+		if have := cachedStructSpecs(); have != n {
+			t.Errorf("Decode() cached %d new struct specs", have-n)
+		}
+	})
+}
+
+// cachedStructSpecs returns the number of cached struct specs.
+func cachedStructSpecs() int {
+	n := 0
+	fieldSpecCache.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestRecord_errors(t *testing.T) {
+	t.Run("decode", func(t *testing.T) {
+		err := Unmarshal([]byte("00x2"), NewRecord(testLayout))
+		var typeErr *UnmarshalTypeError
+		if !errors.As(err, &typeErr) || typeErr.Struct != "Payment" || typeErr.Field != "ID" {
+			t.Errorf("Unmarshal() want *UnmarshalTypeError for ID, have %v", err)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		r := NewRecord(testLayout)
+		r.Values["ID"] = 12345
+		_, err := Marshal(r)
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Struct != "Payment" || overflowErr.Field != "ID" {
+			t.Errorf("Marshal() want *OverflowError for ID, have %v", err)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		r := NewRecord(testLayout)
+		r.Values["Payee"] = 1
+		if _, err := Marshal(r); err == nil {
+			t.Errorf("Marshal() want error")
+		}
+	})
+
+	t.Run("invalid layout", func(t *testing.T) {
+		for _, l := range []*Layout{
+			{},
+			{Fields: []LayoutField{{Name: "A", Start: 2, End: 1}}},
+			{Fields: []LayoutField{{Name: "A", Start: 1, End: 1}, {Name: "A", Start: 2, End: 2}}},
+			{Fields: []LayoutField{{Start: 1, End: 1}}},
+		} {
+			if err := l.Validate(); err == nil {
+				t.Errorf("Validate() want error for %+v", l.Fields)
+			}
+		}
+	})
+}
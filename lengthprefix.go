@@ -0,0 +1,209 @@
+package fixedwidth
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// A LengthPrefix describes the length header that precedes each record of
+// RecordFormatLengthPrefix, as used by many hosts to exchange messages over TCP.
+// The zero value describes a 2 byte big-endian binary length of the record,
+// excluding the header.
+type LengthPrefix struct {
+	// Size is the size of the header in bytes: 2 or 4 for a binary header, and 1 to
+	// 9 for an ASCII header. The default is 2.
+	Size int
+
+	// ASCII reports whether the length is written as zero-padded ASCII decimal
+	// digits, e.g. "0042", instead of a big-endian binary integer.
+	ASCII bool
+
+	// Inclusive reports whether the length includes the header.
+	Inclusive bool
+
+	// MaxLength is the largest accepted length of a record, excluding the header.
+	// The default is the largest length the header can hold.
+	MaxLength int
+}
+
+// size returns the size of the header.
+func (p LengthPrefix) size() int {
+	if p.Size == 0 {
+		return 2
+	}
+	return p.Size
+}
+
+// validate reports whether the size of the header is supported.
+func (p LengthPrefix) validate() error {
+	size := p.size()
+	if p.ASCII && size >= 1 && size <= 9 || !p.ASCII && (size == 2 || size == 4) {
+		return nil
+	}
+	encoding := "binary"
+	if p.ASCII {
+		encoding = "ASCII"
+	}
+	return errors.New("fixedwidth: " + encoding + " length header of " + strconv.Itoa(p.Size) + " bytes is not supported")
+}
+
+// maxLength returns the largest length of a record, excluding the header.
+func (p LengthPrefix) maxLength() int {
+	var n int
+	switch {
+	case p.ASCII:
+		n = 1
+		for i := 0; i < p.size(); i++ {
+			n *= 10
+		}
+		n--
+	case p.size() == 4:
+		n = math.MaxInt32
+	default:
+		n = math.MaxUint16
+	}
+	if p.Inclusive {
+		n -= p.size()
+	}
+	if p.MaxLength > 0 && p.MaxLength < n {
+		n = p.MaxLength
+	}
+	return n
+}
+
+// A LengthPrefixError describes an invalid length header.
+type LengthPrefixError struct {
+	Offset int64  // offset of the header within the input
+	Header []byte // the header
+	Reason string
+}
+
+func (e *LengthPrefixError) Error() string {
+	return "fixedwidth: invalid length header " + strconv.Quote(string(e.Header)) +
+		" at offset " + strconv.FormatInt(e.Offset, 10) + ": " + e.Reason
+}
+
+// SetLengthPrefix configures `Decoder` to read records that are each preceded by a
+// length header described by p, instead of lines. A net.Conn can be read directly,
+// as each call to Decode only reads as far as the records it decodes.
+//
+// A *LengthPrefixError is returned for a header that is invalid or holds a length
+// greater than p.MaxLength, and a *TruncatedRecordError for a final record that is
+// shorter than its header says. Records longer than bufio.MaxScanTokenSize-p.Size
+// bytes are reported as ErrTooLong.
+//
+// A header size that is not supported is reported by Decode.
+//
+// SetLengthPrefix turns off a record length set with SetRecordLength.
+func (d *Decoder) SetLengthPrefix(p LengthPrefix) {
+	d.SetRecordFormat(RecordFormatLengthPrefix)
+	d.lengthPrefix = p
+}
+
+// scanPrefixed splits the input into the payloads of records preceded by a length
+// header.
+func (d *Decoder) scanPrefixed(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	p := d.lengthPrefix
+	if err := p.validate(); err != nil {
+		return 0, nil, err
+	}
+	size := p.size()
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if len(data) < size {
+		if atEOF {
+			return 0, nil, &TruncatedRecordError{Offset: d.offset, Len: len(data), Want: size}
+		}
+		return 0, nil, nil
+	}
+	header := data[:size]
+	invalid := func(reason string) error {
+		return &LengthPrefixError{Offset: d.offset, Header: append([]byte(nil), header...), Reason: reason}
+	}
+
+	var n int
+	switch {
+	case p.ASCII:
+		for _, c := range header {
+			if c < '0' || c > '9' {
+				return 0, nil, invalid("length is not a decimal number")
+			}
+			n = n*10 + int(c-'0')
+		}
+	case size == 4:
+		v := binary.BigEndian.Uint32(header)
+		if v > math.MaxInt32 {
+			return 0, nil, invalid("length " + strconv.FormatUint(uint64(v), 10) + " is too large")
+		}
+		n = int(v)
+	default:
+		n = int(binary.BigEndian.Uint16(header))
+	}
+	if p.Inclusive {
+		if n < size {
+			return 0, nil, invalid("length " + strconv.Itoa(n) + " is shorter than the header")
+		}
+		n -= size
+	}
+	if max := p.maxLength(); n > max {
+		return 0, nil, invalid("length " + strconv.Itoa(n) + " exceeds the maximum of " + strconv.Itoa(max))
+	}
+
+	if len(data) < size+n {
+		if atEOF {
+			return 0, nil, &TruncatedRecordError{Offset: d.offset, Len: len(data) - size, Want: n}
+		}
+		// Request more data.
+		return 0, nil, nil
+	}
+	return size + n, data[size : size+n], nil
+}
+
+// SetLengthPrefix configures `Encoder` to precede each record with a length header
+// described by p, instead of terminating it with a line terminator. Records that are
+// longer than p.MaxLength are reported as an *OverflowError. Each call to Encode
+// writes its records to the underlying writer, e.g. a net.Conn, before returning.
+// A header size that is not supported is reported by Encode.
+//
+// SetLengthPrefix turns off a record length set with SetRecordLength.
+func (e *Encoder) SetLengthPrefix(p LengthPrefix) {
+	e.SetRecordFormat(RecordFormatLengthPrefix)
+	e.lengthPrefix = p
+}
+
+// writePrefixed writes record preceded by its length header.
+func (e *Encoder) writePrefixed(record []byte) error {
+	p := e.lengthPrefix
+	if err := p.validate(); err != nil {
+		return err
+	}
+	size := p.size()
+	if max := p.maxLength(); len(record) > max {
+		return &OverflowError{Width: max, Len: len(record)}
+	}
+
+	n := len(record)
+	if p.Inclusive {
+		n += size
+	}
+	header := make([]byte, size)
+	switch {
+	case p.ASCII:
+		for i := size - 1; i >= 0; i-- {
+			header[i] = byte('0' + n%10)
+			n /= 10
+		}
+	case size == 4:
+		binary.BigEndian.PutUint32(header, uint32(n))
+	default:
+		binary.BigEndian.PutUint16(header, uint16(n))
+	}
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := e.w.Write(record)
+	return err
+}
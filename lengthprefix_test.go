@@ -0,0 +1,142 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLengthPrefix(t *testing.T) {
+	type H struct {
+		Code string `fixed:"1,2"`
+		Name string `fixed:"3,7"`
+	}
+	records := []H{{"01", "foo"}, {"02", "\n"}}
+
+	for _, tt := range []struct {
+		name   string
+		prefix LengthPrefix
+		data   string
+	}{
+		{"binary", LengthPrefix{}, "\x00\x0701foo  \x00\x0702\n    "},
+		{"binary inclusive", LengthPrefix{Size: 4, Inclusive: true}, "\x00\x00\x00\x0B01foo  \x00\x00\x00\x0B02\n    "},
+		{"ASCII", LengthPrefix{Size: 4, ASCII: true}, "000701foo  000702\n    "},
+		{"ASCII inclusive", LengthPrefix{Size: 2, ASCII: true, Inclusive: true}, "0901foo  0902\n    "},
+		{"ASCII 3 digits", LengthPrefix{Size: 3, ASCII: true}, "00701foo  00702\n    "},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// Partial reads are framed correctly.
+			dec := NewDecoder(iotest.OneByteReader(bytes.NewReader([]byte(tt.data))))
+			dec.SetLengthPrefix(tt.prefix)
+			var have []H
+			if err := dec.Decode(&have); err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(have, records) {
+				t.Errorf("Decode() want %q, have %q", records, have)
+			}
+
+			buff := new(bytes.Buffer)
+			enc := NewEncoder(buff)
+			enc.SetLengthPrefix(tt.prefix)
+			if err := enc.Encode(records); err != nil {
+				t.Fatalf("Encode() unexpected error: %v", err)
+			}
+			if buff.String() != tt.data {
+				t.Errorf("Encode() want %q, have %q", tt.data, buff.String())
+			}
+		})
+	}
+
+	t.Run("net.Conn", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		go func() {
+			enc := NewEncoder(client)
+			enc.SetLengthPrefix(LengthPrefix{})
+			for _, r := range records {
+				if err := enc.Encode(r); err != nil {
+					t.Errorf("Encode() unexpected error: %v", err)
+				}
+			}
+		}()
+
+		// Each message is decoded as soon as it has been received.
+		dec := NewDecoder(server)
+		dec.SetLengthPrefix(LengthPrefix{})
+		for _, want := range records {
+			var have H
+			if err := dec.Decode(&have); err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if have != want {
+				t.Errorf("Decode() want %q, have %q", want, have)
+			}
+		}
+	})
+
+	for _, tt := range []struct {
+		name   string
+		prefix LengthPrefix
+		data   string
+	}{
+		{"too long", LengthPrefix{MaxLength: 6}, "\x00\x0701foo  "},
+		{"not a number", LengthPrefix{Size: 4, ASCII: true}, "00x701foo  "},
+		{"shorter than header", LengthPrefix{Inclusive: true}, "\x00\x01"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader([]byte(tt.data)))
+			dec.SetLengthPrefix(tt.prefix)
+			var have []H
+			err := dec.Decode(&have)
+			var prefixErr *LengthPrefixError
+			if !errors.As(err, &prefixErr) || prefixErr.Offset != 0 {
+				t.Errorf("Decode() want *LengthPrefixError, have %v", err)
+			}
+		})
+	}
+
+	t.Run("unsupported size", func(t *testing.T) {
+		for _, p := range []LengthPrefix{{Size: 3}, {Size: 8}, {Size: -1, ASCII: true}, {Size: 10, ASCII: true}} {
+			dec := NewDecoder(bytes.NewReader([]byte("\x00\x00\x0701foo  ")))
+			dec.SetLengthPrefix(p)
+			var have []H
+			if err := dec.Decode(&have); err == nil || !strings.Contains(err.Error(), "not supported") {
+				t.Errorf("Decode() want an unsupported size error for %+v, have %v", p, err)
+			}
+
+			enc := NewEncoder(new(bytes.Buffer))
+			enc.SetLengthPrefix(p)
+			if err := enc.Encode(records); err == nil || !strings.Contains(err.Error(), "not supported") {
+				t.Errorf("Encode() want an unsupported size error for %+v, have %v", p, err)
+			}
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte("\x00\x0701foo  \x00\x0702")))
+		dec.SetLengthPrefix(LengthPrefix{})
+		var have []H
+		err := dec.Decode(&have)
+		var truncErr *TruncatedRecordError
+		if !errors.As(err, &truncErr) || truncErr.Offset != 9 || truncErr.Len != 2 || truncErr.Want != 7 {
+			t.Errorf("Decode() want *TruncatedRecordError, have %v", err)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		enc := NewEncoder(new(bytes.Buffer))
+		enc.SetLengthPrefix(LengthPrefix{MaxLength: 6})
+		err := enc.Encode(records)
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Width != 6 || overflowErr.Len != 7 {
+			t.Errorf("Encode() want *OverflowError, have %v", err)
+		}
+	})
+}
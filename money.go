@@ -0,0 +1,290 @@
+package fixedwidth
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in a currency.
+//
+// Amount fields are linked to the field of the same struct that holds their currency
+// code with the currency tag option, e.g. `fixed:"64,75,currency=CurrencyCode"`. The
+// amount has an implied scale equal to the number of minor units of the currency,
+// see LookupCurrency. Money fields hold both the amount and the currency code of the
+// line; integer, float and Decimal fields can be linked as well.
+//
+// When encoding, the scale is taken from the value of the currency field, or from the
+// Currency of a Money value if the currency field is blank, in which case the Currency
+// is written to the currency field. A Money value in a different currency than its
+// currency field is reported as an error.
+type Money struct {
+	Amount   Decimal
+	Currency string
+}
+
+var moneyType = reflect.TypeOf(Money{})
+
+// String returns the amount followed by the currency code, e.g. "30.42 USD".
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Amount.String()
+	}
+	return m.Amount.String() + " " + m.Currency
+}
+
+// MarshalText implements encoding.TextMarshaler. It is used for Money fields that
+// are not linked to a currency field.
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It is the inverse of
+// MarshalText. Empty text is decoded as zero.
+func (m *Money) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		*m = Money{}
+		return nil
+	}
+	amount, currency := s, ""
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		amount, currency = s[:i], strings.TrimSpace(s[i+1:])
+	}
+	d, err := ParseDecimal(amount)
+	if err != nil {
+		return err
+	}
+	*m = Money{Amount: d, Currency: currency}
+	return nil
+}
+
+// currencySpec links an amount field to the field of the same struct that holds its
+// currency code.
+type currencySpec struct {
+	field    string
+	index    int // index of the currency field, or -1 if it does not exist
+	codeType reflect.Type
+	round    roundingMode
+
+	// encoder and setter encode and decode the amount as a *Decimal with a scale of
+	// 0, i.e. as its coefficient.
+	encoder valueEncoder
+	setter  valueSetter
+}
+
+// newCurrencySpec returns the currencySpec of a field of the struct t with the given
+// options. The amount is encoded as the options describe, or as a display decimal.
+func newCurrencySpec(t reflect.Type, spec *fieldSpec, opts fieldOptions, c codecConfig) *currencySpec {
+	cs := &currencySpec{field: opts.currency, index: -1, round: opts.round}
+	if sf, ok := t.FieldByName(opts.currency); ok && len(sf.Index) == 1 {
+		cs.index, cs.codeType = sf.Index[0], sf.Type
+	}
+	opts.scale, opts.hasScale = 0, true
+	cs.encoder, cs.setter = newFieldCodec(reflect.PtrTo(decimalType), spec, opts, c)
+	return cs
+}
+
+// currencyField returns the spec of the currency field.
+func (cs *currencySpec) currencyField(ss structSpec) (fieldSpec, error) {
+	if cs.index < 0 || !ss.fieldSpecs[cs.index].ok {
+		return fieldSpec{}, errors.New("fixedwidth: currency field " + cs.field + " not found")
+	}
+	return ss.fieldSpecs[cs.index], nil
+}
+
+// setterFor returns the setter of the amount field of line, a line of the struct ss.
+func (cs *currencySpec) setterFor(ss structSpec, line rawValue) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		spec, err := cs.currencyField(ss)
+		if err != nil {
+			return err
+		}
+		cv := reflect.New(cs.codeType).Elem()
+		if err := spec.setter(cv, rawValueFromLine(line, spec.startPos, spec.endPos, spec.format)); err != nil {
+			return err
+		}
+		code, err := currencyCode(cv)
+		if err != nil {
+			return err
+		}
+
+		var d *Decimal
+		if err := cs.setter(reflect.ValueOf(&d).Elem(), raw); err != nil {
+			return err
+		}
+		if d == nil {
+			// The amount is blank.
+			if v.Kind() == reflect.Ptr {
+				return nilSetter(v, raw)
+			}
+			return nil
+		}
+		minorUnits, ok := LookupCurrency(code)
+		if !ok {
+			return &UnknownCurrencyError{Code: code}
+		}
+		amount := Decimal{Coefficient: d.Coefficient, Scale: minorUnits}
+
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Type() == moneyType {
+			v.Set(reflect.ValueOf(Money{Amount: amount, Currency: code}))
+			return nil
+		}
+		return setDecimal(v, amount)
+	}
+}
+
+// encoderOf returns the encoder of the amount field in the currency with the given
+// code. The Currency of a Money value must be the same currency.
+func (cs *currencySpec) encoderOf(code string) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nilEncoder(v)
+			}
+			v = v.Elem()
+		}
+		minorUnits, ok := LookupCurrency(code)
+		if !ok {
+			return rawValue{}, &UnknownCurrencyError{Code: code}
+		}
+
+		var d Decimal
+		var err error
+		if v.Type() == moneyType {
+			m := v.Interface().(Money)
+			if m.Currency != "" && !sameCurrency(m.Currency, code) {
+				return rawValue{}, errors.New("fixedwidth: amount in " + m.Currency +
+					" does not match currency " + code + " of field " + cs.field)
+			}
+			d, err = m.Amount.round(minorUnits, cs.round)
+		} else {
+			d, err = decimalOf(v, minorUnits, cs.round)
+		}
+		if err != nil {
+			return rawValue{}, err
+		}
+		d.Scale = 0
+		return cs.encoder(reflect.ValueOf(&d))
+	}
+}
+
+// withCurrencies returns the spec of sv, a struct of ss, whose amount fields are
+// encoded in the currency held by their currency field. A blank currency field is
+// encoded as the Currency of the first Money amount linked to it instead.
+func (ss structSpec) withCurrencies(sv reflect.Value) (structSpec, error) {
+	codes := make(map[int]string)
+	for i, spec := range ss.fieldSpecs {
+		cs := spec.currency
+		if cs == nil || !spec.ok {
+			continue
+		}
+		if _, err := cs.currencyField(ss); err != nil {
+			return structSpec{}, err
+		}
+		if _, ok := codes[cs.index]; !ok {
+			code, err := currencyCode(sv.Field(cs.index))
+			if err != nil {
+				return structSpec{}, err
+			}
+			codes[cs.index] = code
+		}
+		if fv := reflect.Indirect(sv.Field(i)); codes[cs.index] == "" && fv.IsValid() && fv.Type() == moneyType {
+			codes[cs.index] = fv.Interface().(Money).Currency
+		}
+	}
+	if len(codes) == 0 {
+		return ss, nil
+	}
+
+	line := ss
+	line.fieldSpecs = append([]fieldSpec(nil), ss.fieldSpecs...)
+	for i, spec := range ss.fieldSpecs {
+		if cs := spec.currency; cs != nil && spec.ok {
+			line.fieldSpecs[i].encoder = cs.encoderOf(codes[cs.index])
+		}
+	}
+	for i, code := range codes {
+		if held, _ := currencyCode(sv.Field(i)); held != "" || code == "" {
+			continue
+		}
+		cv, err := currencyValue(sv.Field(i).Type(), code)
+		if err != nil {
+			return structSpec{}, err
+		}
+		enc := ss.fieldSpecs[i].encoder
+		line.fieldSpecs[i].encoder = func(reflect.Value) (rawValue, error) {
+			return enc(cv)
+		}
+	}
+	return line, nil
+}
+
+// currencyCode returns the currency code held by v, the value of a currency field,
+// or "" if it is blank. Numeric codes may be held by integers.
+func currencyCode(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == ebcdicStringType:
+		return strings.TrimSpace(v.Interface().(EbcdicString).S), nil
+	case v.Kind() == reflect.String:
+		return strings.TrimSpace(v.String()), nil
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		if v.Int() == 0 {
+			return "", nil
+		}
+		return strconv.FormatInt(v.Int(), 10), nil
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		if v.Uint() == 0 {
+			return "", nil
+		}
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", errors.New("fixedwidth: cannot hold a currency code in " + v.Type().String())
+}
+
+// currencyValue returns a value of the type t of a currency field that holds code.
+// Integers hold the numeric code of the currency.
+func currencyValue(t reflect.Type, code string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	e := v
+	for e.Kind() == reflect.Ptr {
+		e.Set(reflect.New(e.Type().Elem()))
+		e = e.Elem()
+	}
+	switch {
+	case e.Type() == ebcdicStringType:
+		e.Set(reflect.ValueOf(EbcdicString{S: code}))
+		return v, nil
+	case e.Kind() == reflect.String:
+		e.SetString(code)
+		return v, nil
+	}
+
+	numeric, ok := numericCurrency(code)
+	if !ok {
+		return reflect.Value{}, errors.New("fixedwidth: currency " + code + " has no numeric code for " + t.String())
+	}
+	n, _ := strconv.ParseUint(numeric, 10, 16)
+	switch {
+	case e.Kind() >= reflect.Int && e.Kind() <= reflect.Int64 && !e.OverflowInt(int64(n)):
+		e.SetInt(int64(n))
+	case e.Kind() >= reflect.Uint && e.Kind() <= reflect.Uint64 && !e.OverflowUint(n):
+		e.SetUint(n)
+	default:
+		return reflect.Value{}, errors.New("fixedwidth: cannot hold currency " + code + " in " + t.String())
+	}
+	return v, nil
+}
@@ -0,0 +1,176 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	type H struct {
+		Amount   Money   `fixed:"1,8,currency=Currency"`
+		Currency string  `fixed:"9,11"`
+		Float    float64 `fixed:"12,16,currency=Numeric"`
+		Minor    *int64  `fixed:"17,21,currency=Numeric"`
+		Numeric  int     `fixed:"22,24"`
+	}
+
+	for _, tt := range []struct {
+		data string
+		want H
+	}{
+		{"00003042USD0012300042840", H{Money{Decimal{3042, 2}, "USD"}, "USD", 1.23, ptrInt64(42), 840}},
+		{"00003042JPY0012300042392", H{Money{Decimal{3042, 0}, "JPY"}, "JPY", 123, ptrInt64(42), 392}},
+		{"00003042KWD0012300042414", H{Money{Decimal{3042, 3}, "KWD"}, "KWD", 0.123, ptrInt64(42), 414}},
+		{"-0000001CLF00000     36 ", H{Money{Decimal{-1, 4}, "CLF"}, "CLF", 0, nil, 36}},
+	} {
+		var have H
+		if err := Unmarshal([]byte(tt.data), &have); err != nil {
+			t.Fatalf("Unmarshal(%q) unexpected error: %v", tt.data, err)
+		}
+		if have.Amount != tt.want.Amount || have.Float != tt.want.Float || !eqInt64Ptr(have.Minor, tt.want.Minor) {
+			t.Errorf("Unmarshal(%q) want %+v, have %+v", tt.data, tt.want, have)
+		}
+
+		o, err := Marshal(tt.want)
+		if err != nil {
+			t.Fatalf("Marshal() unexpected error: %v", err)
+		}
+		if string(o) != tt.data {
+			t.Errorf("Marshal() want %q, have %q", tt.data, o)
+		}
+	}
+
+	t.Run("rescale", func(t *testing.T) {
+		v := H{Amount: Money{Amount: Decimal{30, 0}}, Currency: "KWD", Numeric: 392, Float: 1.6}
+		o, err := Marshal(v)
+		if want := "00030000KWD00002     392"; err != nil || string(o) != want {
+			t.Errorf("Marshal() want %q, have %q, %v", want, o, err)
+		}
+	})
+
+	t.Run("currency of Money", func(t *testing.T) {
+		for _, tt := range []struct {
+			v    H
+			want string
+		}{
+			// A blank currency field is encoded as the currency of the Money value.
+			{H{Amount: Money{Decimal{1234, 2}, "USD"}, Numeric: 840}, "00001234USD00000     840"},
+			{H{Amount: Money{Decimal{1234, 2}, "USD"}, Currency: "usd", Numeric: 840}, "00001234usd00000     840"},
+			{H{Amount: Money{Decimal{1234, 2}, "840"}, Currency: "USD", Numeric: 840}, "00001234USD00000     840"},
+		} {
+			o, err := Marshal(tt.v)
+			if err != nil || string(o) != tt.want {
+				t.Errorf("Marshal(%+v) want %q, have %q, %v", tt.v, tt.want, o, err)
+			}
+		}
+	})
+
+	t.Run("numeric currency field", func(t *testing.T) {
+		type N struct {
+			Amount Money  `fixed:"1,8,currency=Code"`
+			Code   *int16 `fixed:"9,11,right,0"`
+		}
+		o, err := Marshal(N{Amount: Money{Decimal{1234, 2}, "USD"}})
+		if want := "00001234840"; err != nil || string(o) != want {
+			t.Errorf("Marshal() want %q, have %q, %v", want, o, err)
+		}
+	})
+
+	t.Run("code page", func(t *testing.T) {
+		want := H{Money{Decimal{3042, 2}, "USD"}, "USD", 1.23, ptrInt64(42), 840}
+		var buff bytes.Buffer
+		enc := NewEncoder(&buff)
+		enc.SetCodePage(CodePage037)
+		if err := enc.Encode(want); err != nil {
+			t.Fatalf("Encode() unexpected error: %v", err)
+		}
+		dec := NewDecoder(&buff)
+		dec.SetCodePage(CodePage037)
+		var have H
+		if err := dec.Decode(&have); err != nil || have.Amount != want.Amount || !eqInt64Ptr(have.Minor, want.Minor) {
+			t.Errorf("Decode() want %+v, have %+v, %v", want, have, err)
+		}
+	})
+
+	t.Run("override", func(t *testing.T) {
+		RegisterCurrency("ISK", 2)
+		defer RegisterCurrency("ISK", 0)
+		var have H
+		if err := Unmarshal([]byte("00003042isk"), &have); err != nil || have.Amount.Amount != (Decimal{3042, 2}) {
+			t.Errorf("Unmarshal() want 30.42 ISK, have %v, %v", have.Amount, err)
+		}
+	})
+}
+
+func TestMoney_errors(t *testing.T) {
+	type H struct {
+		Amount   Money  `fixed:"1,8,currency=Currency"`
+		Currency string `fixed:"9,11"`
+	}
+	type M struct {
+		Amount Money `fixed:"1,8,currency=Missing"`
+	}
+
+	var h H
+	err := Unmarshal([]byte("00003042XXX"), &h)
+	var currencyErr *UnknownCurrencyError
+	if !errors.As(err, &currencyErr) || currencyErr.Code != "XXX" {
+		t.Errorf("Unmarshal() want *UnknownCurrencyError, have %v", err)
+	}
+	if _, err := Marshal(H{Amount: Money{Amount: Decimal{1, 0}}}); !errors.As(err, &currencyErr) {
+		t.Errorf("Marshal() want *UnknownCurrencyError, have %v", err)
+	}
+
+	if _, err := Marshal(H{Amount: Money{Decimal{1234, 2}, "USD"}, Currency: "JPY"}); err == nil {
+		t.Errorf("Marshal() want error for an amount in a different currency")
+	}
+
+	var m M
+	if err := Unmarshal([]byte("00003042"), &m); err == nil {
+		t.Errorf("Unmarshal() want error for a missing currency field")
+	}
+	if _, err := Marshal(M{}); err == nil {
+		t.Errorf("Marshal() want error for a missing currency field")
+	}
+}
+
+func TestLookupCurrency(t *testing.T) {
+	for _, tt := range []struct {
+		code string
+		want int
+	}{
+		{"USD", 2}, {"840", 2}, {"jpy", 0}, {"392", 0}, {"KWD", 3}, {"36", 2}, {" EUR ", 2},
+	} {
+		if have, ok := LookupCurrency(tt.code); !ok || have != tt.want {
+			t.Errorf("LookupCurrency(%q) want %d, have %d, %v", tt.code, tt.want, have, ok)
+		}
+	}
+	if _, ok := LookupCurrency("ABC"); ok {
+		t.Errorf("LookupCurrency(%q) want not ok", "ABC")
+	}
+
+	t.Run("override", func(t *testing.T) {
+		RegisterCurrency("CLP", 2)
+		defer RegisterCurrency("CLP", 0)
+		RegisterCurrency("986", 3)
+		defer RegisterCurrency("BRL", 2)
+
+		for _, tt := range []struct {
+			code string
+			want int
+		}{
+			{"CLP", 2}, {"152", 2}, {"BRL", 3}, {"986", 3},
+		} {
+			if have, ok := LookupCurrency(tt.code); !ok || have != tt.want {
+				t.Errorf("LookupCurrency(%q) want %d, have %d, %v", tt.code, tt.want, have, ok)
+			}
+		}
+	})
+}
+
+func ptrInt64(i int64) *int64 { return &i }
+
+func eqInt64Ptr(a, b *int64) bool {
+	return a == b || a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,130 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// numberStyle describes how a formatted number is encoded. Formatted numbers are
+// written as text with an explicit decimal point, e.g. "-12.35", and the sign style
+// of the field.
+//
+// Right-aligned numbers padded with zeros are padded after a leading sign, e.g.
+// "-0005" rather than "000-5". Numbers with a separate sign are padded between the
+// sign and the digits.
+type numberStyle struct {
+	sign    signStyle
+	prec    int
+	hasPrec bool
+	round   roundingMode
+}
+
+// isFormattedNumber reports whether a field of type t with the given options is a
+// formatted number. Fields without a sign, prec or round option keep the default
+// encoding, e.g. "000-5" for -5 in a right-aligned field padded with zeros.
+func isFormattedNumber(t reflect.Type, opts fieldOptions) bool {
+	return isNumericKind(t) && (opts.hasSign || opts.hasPrec || opts.hasRound)
+}
+
+// exactDecimalOf returns the value of v as a Decimal with as many digits after the
+// decimal point as it needs. Integers are whole numbers, unlike in decimalOf.
+func exactDecimalOf(v reflect.Value) (Decimal, error) {
+	if v.Type() == decimalType {
+		return v.Interface().(Decimal), nil
+	}
+	switch v.Kind() {
+	case reflect.Float64, reflect.Float32:
+		d, err := ParseDecimal(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()))
+		if err != nil {
+			return Decimal{}, errDecimalRange
+		}
+		return d, nil
+	}
+	return decimalOf(v, 0, roundHalfUp)
+}
+
+// numberEncoder encodes a formatted number field of type t described by spec.
+// Floats have two digits after the decimal point, and integers none, unless a
+// precision is given.
+func numberEncoder(t reflect.Type, s numberStyle, spec fieldSpec, c codecConfig) valueEncoder {
+	prec := -1
+	switch {
+	case s.hasPrec:
+		prec = s.prec
+	case indirectType(t).Kind() == reflect.Float64 || indirectType(t).Kind() == reflect.Float32:
+		prec = 2
+	}
+	width, format := spec.len(), spec.format
+
+	return func(v reflect.Value) (rawValue, error) {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nilEncoder(v)
+			}
+			v = v.Elem()
+		}
+		d, err := exactDecimalOf(v)
+		if err == nil && prec >= 0 {
+			d, err = d.round(prec, s.round)
+		}
+		if err != nil {
+			return rawValue{}, err
+		}
+
+		digits := strings.TrimPrefix(d.String(), "-")
+		prefix, suffix := s.sign.affixes(d.Coefficient < 0)
+		n := width - len(prefix) - len(digits) - len(suffix)
+		if n < 0 {
+			return rawValue{}, &OverflowError{Width: width, Len: width - n}
+		}
+		if !s.sign.separate() && (format.alignment != right || format.padChar != '0') {
+			return newRawValue(prefix+digits+suffix, false)
+		}
+		padding := string(bytes.Repeat([]byte{format.padChar}, n))
+		if format.alignment == right {
+			return newRawValue(prefix+padding+digits+suffix, false)
+		}
+		return newRawValue(prefix+digits+padding+suffix, false)
+	}
+}
+
+// numberSetter decodes a formatted number field. Any sign style is accepted, and
+// integers are rounded with the rounding mode of s.
+func numberSetter(s numberStyle, c codecConfig) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		if isBlank([]byte(raw.data)) {
+			if v.Kind() == reflect.Ptr {
+				return nilSetter(v, raw)
+			}
+			return nil
+		}
+		digits, negative, ok := parseSigned([]byte(raw.data))
+		if !ok {
+			return &strconv.NumError{Func: "parseNumber", Num: raw.data, Err: strconv.ErrSyntax}
+		}
+		d, err := ParseDecimal(string(digits))
+		if err != nil {
+			return err
+		}
+		if negative {
+			d.Coefficient = -d.Coefficient
+		}
+
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8,
+			reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+			if d, err = d.round(0, s.round); err != nil {
+				return err
+			}
+		}
+		return setDecimalSaturating(v, d, c.saturate)
+	}
+}
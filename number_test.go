@@ -0,0 +1,136 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	type H struct {
+		Padded   int      `fixed:"1,5,right,0,sign=minus"`
+		Float    float64  `fixed:"6,12,right,0,prec=3"`
+		Plus     int      `fixed:"13,16,right,0,sign=plus"`
+		Trailing int      `fixed:"17,20,right,0,sign=trailing-minus"`
+		Leading  float64  `fixed:"21,26,right,_,sign=leading,prec=1"`
+		Decimal  Decimal  `fixed:"27,31,left,_,prec=1,round=down"`
+		Ptr      *float32 `fixed:"32,36,right,0"`
+		Display  int      `fixed:"37,40,scale=0,sign=trailing-minus"`
+	}
+
+	data := "-0005" + "-01.500" + "+042" + "012-" + "-  2.5" + "1.9  " + "00000" + "042-"
+	want := H{-5, -1.5, 42, -12, -2.5, Decimal{19, 1}, nil, -42}
+
+	var have H
+	if err := Unmarshal([]byte(data), &have); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if have != want {
+		t.Errorf("Unmarshal() want %+v, have %+v", want, have)
+	}
+
+	want.Decimal = Decimal{199, 2}
+	o, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if string(o) != data {
+		t.Errorf("Marshal() want %q, have %q", data, o)
+	}
+
+	t.Run("positive", func(t *testing.T) {
+		v := H{5, 1.0005, 0, 12, 2.46, Decimal{5, 0}, new(float32), 42}
+		data := "00005" + "001.001" + "+000" + "0012" + "+  2.5" + "5.0  " + "00.00" + "0042"
+		o, err := Marshal(v)
+		if err != nil || string(o) != data {
+			t.Errorf("Marshal() want %q, have %q, %v", data, o, err)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		_, err := Marshal(H{Padded: -12345})
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Field != "Padded" || overflowErr.Len != 6 {
+			t.Errorf("Marshal() want *OverflowError, have %v", err)
+		}
+	})
+
+	t.Run("unpadded overflow", func(t *testing.T) {
+		for _, v := range []interface{}{
+			struct {
+				C int `fixed:"1,2,sign=plus"`
+			}{123},
+			struct {
+				B float64 `fixed:"1,3,prec=3"`
+			}{1.5},
+		} {
+			// Formatted numbers overflow whatever the mode.
+			err := NewEncoder(new(bytes.Buffer)).Encode(v)
+			var overflowErr *OverflowError
+			if !errors.As(err, &overflowErr) || overflowErr.Width >= overflowErr.Len {
+				t.Errorf("Encode(%+v) want *OverflowError, have %v", v, err)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var v H
+		err := Unmarshal([]byte("-+005"), &v)
+		var typeErr *UnmarshalTypeError
+		if !errors.As(err, &typeErr) || typeErr.Field != "Padded" {
+			t.Errorf("Unmarshal() want *UnmarshalTypeError, have %v", err)
+		}
+	})
+}
+
+func TestNumber_default(t *testing.T) {
+	// Right-aligned numbers padded with zeros without a sign, prec or round option
+	// keep the default encoding, with the padding before the sign.
+	type H struct {
+		Int   int     `fixed:"1,5,right,0"`
+		Float float64 `fixed:"6,12,right,0"`
+	}
+
+	data := "000-5" + "00-1.50"
+	want := H{-5, -1.5}
+
+	var have H
+	if err := Unmarshal([]byte(data), &have); err != nil || have != want {
+		t.Errorf("Unmarshal() want %+v, have %+v, %v", want, have, err)
+	}
+	o, err := Marshal(want)
+	if err != nil || string(o) != data {
+		t.Errorf("Marshal() want %q, have %q, %v", data, o, err)
+	}
+}
+
+func TestDecimal_round(t *testing.T) {
+	for _, tt := range []struct {
+		d    Decimal
+		mode string
+		want int64
+	}{
+		{Decimal{125, 2}, "halfup", 13},
+		{Decimal{-125, 2}, "halfup", -13},
+		{Decimal{125, 2}, "halfeven", 12},
+		{Decimal{135, 2}, "halfeven", 14},
+		{Decimal{-125, 2}, "halfeven", -12},
+		{Decimal{126, 2}, "halfeven", 13},
+		{Decimal{129, 2}, "down", 12},
+		{Decimal{-129, 2}, "down", -12},
+		{Decimal{121, 2}, "up", 13},
+		{Decimal{-121, 2}, "up", -13},
+		{Decimal{129, 2}, "floor", 12},
+		{Decimal{-121, 2}, "floor", -13},
+		{Decimal{121, 2}, "ceiling", 13},
+		{Decimal{-129, 2}, "ceiling", -12},
+		{Decimal{120, 2}, "up", 12},
+		{Decimal{1, 30}, "up", 1},
+		{Decimal{1, 30}, "halfup", 0},
+	} {
+		have, err := tt.d.round(1, roundingModes[tt.mode])
+		if err != nil || have != (Decimal{tt.want, 1}) {
+			t.Errorf("%v.round(1, %s) want %d, have %v, %v", tt.d, tt.mode, tt.want, have, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package fixedwidth
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// groupSpec describes a repeating group (COBOL OCCURS): an array or slice field whose
+// elements are encoded one after another in sub-intervals of equal width.
+type groupSpec struct {
+	count int
+	elem  fieldSpec // spec of the first element, positioned at the start of the field
+}
+
+// newGroupSpec returns the groupSpec of an array or slice field of type t with the
+// given options. spec is the spec of the whole field, which is encoded and decoded
+// without padding or trimming; the format applies to each element instead.
+func newGroupSpec(t reflect.Type, spec *fieldSpec, opts fieldOptions, c codecConfig) (*groupSpec, error) {
+	if t.Kind() != reflect.Array && t.Kind() != reflect.Slice {
+		return nil, errors.New("fixedwidth: occurs option on non-array field of type " + t.String())
+	}
+	if t.Kind() == reflect.Array && t.Len() != opts.occurs {
+		return nil, errors.New("fixedwidth: occurs=" + strconv.Itoa(opts.occurs) + " does not match " + t.String())
+	}
+	if spec.len()%opts.occurs != 0 {
+		return nil, errors.New("fixedwidth: field of " + strconv.Itoa(spec.len()) +
+			" characters cannot be split into " + strconv.Itoa(opts.occurs) + " elements")
+	}
+
+	g := &groupSpec{count: opts.occurs, elem: *spec}
+	g.elem.startPos = 1
+	g.elem.endPos = spec.len() / opts.occurs
+	if indirectType(t.Elem()).Kind() == reflect.Struct && !t.Elem().Implements(textUnmarshalerType) {
+		// Positions within a struct element are relative to its start.
+		g.elem.format.alignment = alignmentNone
+	}
+	opts.occurs = 0
+	g.elem.encoder, g.elem.setter = newFieldCodec(t.Elem(), &g.elem, opts, c)
+	spec.format.alignment = alignmentNone
+	return g, nil
+}
+
+// width returns the width of an element.
+func (g *groupSpec) width() int {
+	return g.elem.len()
+}
+
+// setter decodes each element from its own sub-interval. A slice is set to count
+// elements.
+func (g *groupSpec) setter(v reflect.Value, raw rawValue) error {
+	return g.decode(v, raw, g.count)
+}
+
+// decode decodes the first n elements of the group.
+func (g *groupSpec) decode(v reflect.Value, raw rawValue, n int) error {
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	}
+	w := g.width()
+	for i := 0; i < n; i++ {
+		elem := rawValueFromLine(raw, i*w+1, (i+1)*w, g.elem.format)
+		if err := g.elem.setter(v.Index(i), elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encoder encodes each element in order. The sub-intervals of missing elements of a
+// short slice are filled with the padding character.
+func (g *groupSpec) encoder(c codecConfig) valueEncoder {
+	return g.encoderOf(g.count, c)
+}
+
+// encoderOf returns an encoder of a group of n elements.
+func (g *groupSpec) encoderOf(n int, c codecConfig) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		w := g.width()
+		if v.Len() > n {
+			return rawValue{}, &OverflowError{Width: n * w, Len: v.Len() * w}
+		}
+
+		capacity := n * w
+		if c.useCodepointIndices {
+			capacity = int(1.1*float64(capacity)) + 1
+		}
+		b := newLineBuilder(n*w, capacity, g.elem.format.padChar)
+		for i := 0; i < v.Len(); i++ {
+			spec := g.elem
+			spec.startPos, spec.endPos = i*w+1, (i+1)*w
+			if err := g.elem.encoder.Write(b, v.Index(i), spec); err != nil {
+				return rawValue{}, err
+			}
+		}
+		return b.AsRawValue(), nil
+	}
+}
+
+// newGroupCodec returns the encoder and setter of a field with the occurs tag option.
+// An occurs option that does not suit the field is reported when the field is used.
+func newGroupCodec(t reflect.Type, spec *fieldSpec, opts fieldOptions, c codecConfig) (valueEncoder, valueSetter) {
+	g, err := newGroupSpec(t, spec, opts, c)
+	if err != nil {
+		return errorCodec(err)
+	}
+	return g.encoder(c), g.setter
+}
+
+// errorCodec returns an encoder and setter that report err, for a field whose tag
+// options do not suit it.
+func errorCodec(err error) (valueEncoder, valueSetter) {
+	return func(reflect.Value) (rawValue, error) { return rawValue{}, err },
+		func(reflect.Value, rawValue) error { return err }
+}
@@ -0,0 +1,100 @@
+package fixedwidth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestOccurs(t *testing.T) {
+	type Installment struct {
+		Number int     `fixed:"1,2,right,0"`
+		Amount float64 `fixed:"3,8,scale=2"`
+	}
+	type H struct {
+		Codes        [3]string     `fixed:"1,6,occurs=3"`
+		Counts       []int         `fixed:"7,15,occurs=3,right,0"`
+		Installments []Installment `fixed:"16,31,occurs=2"`
+		Flags        [2]*bool      `fixed:"32,33,occurs=2"`
+	}
+
+	tr := true
+	for _, tt := range []struct {
+		name string
+		data string
+		want H
+	}{
+		{
+			name: "full",
+			data: "A B CD001020300" + "01001000" + "02002500" + "t ",
+			want: H{
+				Codes:        [3]string{"A", "B", "CD"},
+				Counts:       []int{1, 20, 300},
+				Installments: []Installment{{1, 10}, {2, 25}},
+				Flags:        [2]*bool{&tr, nil},
+			},
+		},
+		{
+			name: "blank",
+			data: "      000000000" + "00000000" + "00000000" + "  ",
+			want: H{
+				Counts:       []int{0, 0, 0},
+				Installments: []Installment{{}, {}},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var have H
+			if err := Unmarshal([]byte(tt.data), &have); err != nil {
+				t.Fatalf("Unmarshal(%q) unexpected error: %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("Unmarshal(%q) want %+v, have %+v", tt.data, tt.want, have)
+			}
+
+			o, err := Marshal(tt.want)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+			if string(o) != tt.data {
+				t.Errorf("Marshal() want %q, have %q", tt.data, o)
+			}
+		})
+	}
+
+	t.Run("short slice", func(t *testing.T) {
+		o, err := Marshal(H{Counts: []int{7}})
+		if want := "      007000000"; err != nil || string(o[:15]) != want {
+			t.Errorf("Marshal() want %q, have %q, %v", want, o[:15], err)
+		}
+	})
+
+	t.Run("long slice", func(t *testing.T) {
+		_, err := Marshal(H{Counts: []int{1, 2, 3, 4}})
+		var oe *OverflowError
+		if !errors.As(err, &oe) || oe.Field != "Counts" {
+			t.Errorf("Marshal() want *OverflowError for Counts, have %v", err)
+		}
+	})
+}
+
+func TestOccurs_invalid(t *testing.T) {
+	for _, v := range []interface{}{
+		&struct {
+			A int `fixed:"1,6,occurs=3"`
+		}{},
+		&struct {
+			A [2]int `fixed:"1,6,occurs=3"`
+		}{},
+		&struct {
+			A []int `fixed:"1,7,occurs=3"`
+		}{},
+	} {
+		if err := Unmarshal([]byte("1234567"), v); err == nil {
+			t.Errorf("Unmarshal(%T) want error", v)
+		}
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("Marshal(%T) want error", v)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package fixedwidth
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Packed decimal (COBOL COMP-3) fields store two digits per byte. The low nibble of
+// the last byte holds the sign: 0xC or 0xF for positive numbers and 0xD for negative
+// numbers. A field of n bytes holds 2n-1 digits.
+const (
+	packedPositive = 0x0C
+	packedNegative = 0x0D
+	packedUnsigned = 0x0F
+)
+
+// An InvalidNibbleError describes a nibble of a packed decimal field that is neither
+// a digit nor a valid sign.
+type InvalidNibbleError struct {
+	Offset int  // offset of the byte holding the nibble within the field
+	Nibble byte // the invalid nibble
+}
+
+func (e *InvalidNibbleError) Error() string {
+	return fmt.Sprintf("fixedwidth: invalid packed decimal nibble 0x%X at offset %d", e.Nibble, e.Offset)
+}
+
+// unpackDecimal decodes a packed decimal with the given scale.
+func unpackDecimal(b []byte, scale int) (d Decimal, ok bool, err error) {
+	if len(b) == 0 {
+		return Decimal{}, false, nil
+	}
+
+	var c int64
+	for i, x := range b {
+		for j, nibble := range [2]byte{x >> 4, x & 0x0F} {
+			if i == len(b)-1 && j == 1 {
+				break
+			}
+			if nibble > 9 {
+				return Decimal{}, false, &InvalidNibbleError{Offset: i, Nibble: nibble}
+			}
+			if c > (math.MaxInt64-int64(nibble))/10 {
+				return Decimal{}, false, errDecimalRange
+			}
+			c = c*10 + int64(nibble)
+		}
+	}
+
+	switch sign := b[len(b)-1] & 0x0F; sign {
+	case 0x0D, 0x0B:
+		c = -c
+	case 0x0C, 0x0F, 0x0A, 0x0E:
+	default:
+		return Decimal{}, false, &InvalidNibbleError{Offset: len(b) - 1, Nibble: sign}
+	}
+	return Decimal{Coefficient: c, Scale: scale}, true, nil
+}
+
+// packDecimal encodes the coefficient of d as a packed decimal of width bytes. If
+// unsigned is true, the sign nibble 0xF is used for positive numbers.
+func packDecimal(d Decimal, width int, unsigned bool) ([]byte, error) {
+	c := d.Coefficient
+	sign := byte(packedPositive)
+	switch {
+	case c < 0 && unsigned:
+		return nil, errDecimalRange
+	case c < 0:
+		sign = packedNegative
+	case unsigned:
+		sign = packedUnsigned
+	}
+
+	b := make([]byte, width)
+	b[width-1] = sign
+	for n := 0; c != 0; n++ {
+		digit := c % 10
+		if digit < 0 {
+			digit = -digit
+		}
+		c /= 10
+
+		// Digits are filled from the right, starting next to the sign nibble.
+		i := width - 1 - (n+1)/2
+		if i < 0 {
+			// The lengths are in bytes, the digits and the sign taking a nibble each.
+			return nil, &OverflowError{Width: width, Len: (n + 1 + countDigits(c) + 2) / 2}
+		}
+		if n%2 == 0 {
+			b[i] |= byte(digit) << 4
+		} else {
+			b[i] |= byte(digit)
+		}
+	}
+	return b, nil
+}
+
+// countDigits returns the number of decimal digits in c, or 0 if c is 0.
+func countDigits(c int64) int {
+	n := 0
+	for ; c != 0; c /= 10 {
+		n++
+	}
+	return n
+}
+
+// packedSetter decodes a packed decimal field with the given scale.
+func packedSetter(scale int, c codecConfig) valueSetter {
+	return numericSetter(func(b []byte) (Decimal, bool, error) {
+		return unpackDecimal(b, scale)
+	}, c)
+}
+
+// packedEncoder encodes a packed decimal field of width bytes with the given scale.
+func packedEncoder(t reflect.Type, width, scale int, mode roundingMode, c codecConfig) valueEncoder {
+	unsigned := isUnsignedKind(t)
+	return numericEncoder(scale, mode, func(d Decimal) ([]byte, error) {
+		return packDecimal(d, width, unsigned)
+	}, c)
+}
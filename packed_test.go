@@ -0,0 +1,86 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPacked(t *testing.T) {
+	type H struct {
+		Int     int64   `fixed:"1,3,comp3"`
+		Uint    uint32  `fixed:"4,5,comp3"`
+		Float   float64 `fixed:"6,8,comp3,scale=2"`
+		Decimal Decimal `fixed:"9,12,comp3,scale=3"`
+		Ptr     *int    `fixed:"13,14,comp3"`
+		Minor   int     `fixed:"15,17,comp3,scale=2"`
+		After   string  `fixed:"18,20"`
+	}
+
+	data := []byte{
+		0x12, 0x34, 0x5C, // 12345
+		0x01, 0x2F, // 12, unsigned
+		0x00, 0x30, 0x4D, // -3.04
+		0x00, 0x12, 0x34, 0x5C, // 12.345
+		0x00, 0x7C, // 7
+		0x00, 0x30, 0x4C, // 3.04 as 304
+		'a', 'b', 'c',
+	}
+	seven := 7
+	want := H{12345, 12, -3.04, Decimal{12345, 3}, &seven, 304, "abc"}
+
+	var have H
+	if err := Unmarshal(data, &have); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if have.Int != want.Int || have.Uint != want.Uint || have.Float != want.Float ||
+		have.Decimal != want.Decimal || have.Ptr == nil || *have.Ptr != 7 ||
+		have.Minor != want.Minor || have.After != want.After {
+		t.Errorf("Unmarshal() want %+v, have %+v", want, have)
+	}
+
+	o, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, o) {
+		t.Errorf("Marshal() want %X, have %X", data, o)
+	}
+}
+
+func TestPacked_errors(t *testing.T) {
+	type H struct {
+		F1 int `fixed:"1,2,comp3"`
+	}
+
+	for _, tt := range []struct {
+		name   string
+		data   []byte
+		offset int
+		nibble byte
+	}{
+		{"invalid digit", []byte{0x1A, 0x2C}, 0, 0xA},
+		{"invalid sign", []byte{0x12, 0x34}, 1, 0x4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var v H
+			err := Unmarshal(tt.data, &v)
+			var typeErr *UnmarshalTypeError
+			if !errors.As(err, &typeErr) || typeErr.Field != "F1" {
+				t.Fatalf("Unmarshal() want *UnmarshalTypeError, have %v", err)
+			}
+			var nibbleErr *InvalidNibbleError
+			if !errors.As(err, &nibbleErr) || nibbleErr.Offset != tt.offset || nibbleErr.Nibble != tt.nibble {
+				t.Errorf("Unmarshal() want *InvalidNibbleError{%d, %X}, have %v", tt.offset, tt.nibble, err)
+			}
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		_, err := Marshal(H{F1: 1234})
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Field != "F1" || overflowErr.Width != 2 || overflowErr.Len != 3 {
+			t.Errorf("Marshal() want *OverflowError, have %v", err)
+		}
+	})
+}
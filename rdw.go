@@ -0,0 +1,216 @@
+package fixedwidth
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// A RecordFormat describes how records are framed in a stream.
+type RecordFormat int
+
+const (
+	// RecordFormatLine frames records as lines ending with the line terminator. It is
+	// the default.
+	RecordFormatLine RecordFormat = iota
+
+	// RecordFormatV frames each record with a 4 byte Record Descriptor Word (RDW), as
+	// in z/OS datasets with RECFM=V transferred in binary. The RDW holds the length
+	// of the record, including the RDW, as a big-endian 2 byte integer followed by 2
+	// zero bytes.
+	RecordFormatV
+
+	// RecordFormatVB groups records framed by RDWs into blocks, each starting with a
+	// 4 byte Block Descriptor Word (BDW), as in datasets with RECFM=VB. The BDW holds
+	// the length of the block, including the BDW, in the same form as an RDW, or as a
+	// 4 byte integer with the high bit set (an extended BDW).
+	RecordFormatVB
+
+	// RecordFormatLengthPrefix frames each record with a length header, as described
+	// by a LengthPrefix. It is set with SetLengthPrefix.
+	RecordFormatLengthPrefix
+)
+
+// maxBlockSize is the largest block size of a z/OS dataset, and the default block
+// size used by the Encoder.
+const maxBlockSize = 32760
+
+// A DescriptorWordError describes an invalid Record or Block Descriptor Word.
+type DescriptorWordError struct {
+	Offset int64  // offset of the descriptor word within the input
+	Word   []byte // the descriptor word
+	Reason string
+}
+
+func (e *DescriptorWordError) Error() string {
+	return "fixedwidth: invalid descriptor word " + strconv.Quote(string(e.Word)) +
+		" at offset " + strconv.FormatInt(e.Offset, 10) + ": " + e.Reason
+}
+
+// SetRecordFormat configures `Decoder` to frame records with f instead of line
+// terminators. The payload of each record is decoded as a line.
+//
+// SetRecordFormat turns off a record length set with SetRecordLength.
+func (d *Decoder) SetRecordFormat(f RecordFormat) {
+	d.recordFormat = f
+	d.recordLength = 0
+	d.blockLeft = 0
+}
+
+// scanVariable splits the input into the payloads of records framed by RDWs, and
+// BDWs if the records are blocked.
+func (d *Decoder) scanVariable(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// BDWs and padding are skipped in the same call, as the scanner stops at EOF when
+	// no token is returned.
+	for {
+		n, token, err := d.scanDescriptor(data[advance:], atEOF, d.offset+int64(advance))
+		advance += n
+		if err != nil || token != nil || n == 0 {
+			return advance, token, err
+		}
+	}
+}
+
+// scanDescriptor reads a single descriptor word at offset and returns the payload of
+// the record it describes, or nil if it describes a block or padding.
+func (d *Decoder) scanDescriptor(data []byte, atEOF bool, offset int64) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		if d.blockLeft > 0 {
+			return 0, nil, &TruncatedRecordError{Offset: offset, Want: d.blockLeft}
+		}
+		return 0, nil, nil
+	}
+	if len(data) < 4 {
+		if atEOF {
+			return 0, nil, &TruncatedRecordError{Offset: offset, Len: len(data), Want: 4}
+		}
+		return 0, nil, nil
+	}
+	word := data[:4]
+	invalid := func(reason string) error {
+		return &DescriptorWordError{Offset: offset, Word: append([]byte(nil), word...), Reason: reason}
+	}
+
+	if d.recordFormat == RecordFormatVB && d.blockLeft == 0 {
+		var n int
+		if word[0]&0x80 != 0 {
+			n = int(binary.BigEndian.Uint32(word) &^ (1 << 31))
+		} else if word[2] != 0 || word[3] != 0 {
+			return 0, nil, invalid("reserved bytes of BDW are not zero")
+		} else {
+			n = int(binary.BigEndian.Uint16(word))
+		}
+		if n < 8 {
+			return 0, nil, invalid("block length " + strconv.Itoa(n) + " is too short")
+		}
+		// Skip the BDW; the records of the block are returned by the following calls.
+		d.blockLeft = n - 4
+		return 4, nil, nil
+	}
+
+	n := int(binary.BigEndian.Uint16(word))
+	if word[2] != 0 || word[3] != 0 {
+		return 0, nil, invalid("spanned records are not supported")
+	}
+	if n == 0 && d.recordFormat == RecordFormatVB {
+		// The rest of the block is padding.
+		if len(data) < d.blockLeft {
+			if atEOF {
+				return 0, nil, &TruncatedRecordError{Offset: offset, Len: len(data), Want: d.blockLeft}
+			}
+			return 0, nil, nil
+		}
+		advance, d.blockLeft = d.blockLeft, 0
+		return advance, nil, nil
+	}
+	if n < 4 {
+		return 0, nil, invalid("record length " + strconv.Itoa(n) + " is too short")
+	}
+	if d.recordFormat == RecordFormatVB && n > d.blockLeft {
+		return 0, nil, invalid("record length " + strconv.Itoa(n) + " exceeds the remaining " + strconv.Itoa(d.blockLeft) + " bytes of the block")
+	}
+	if len(data) < n {
+		if atEOF {
+			return 0, nil, &TruncatedRecordError{Offset: offset, Len: len(data), Want: n}
+		}
+		return 0, nil, nil
+	}
+	if d.recordFormat == RecordFormatVB {
+		d.blockLeft -= n
+	}
+	return n, data[4:n], nil
+}
+
+// SetRecordFormat configures `Encoder` to frame records with f instead of line
+// terminators. Blocks of RecordFormatVB are filled up to the block size across calls
+// to Encode, see SetBlockSize. The last block is only written by Flush, which must be
+// called after the last call to Encode.
+//
+// SetRecordFormat turns off a record length set with SetRecordLength.
+func (e *Encoder) SetRecordFormat(f RecordFormat) {
+	e.recordFormat = f
+	e.recordLength = 0
+}
+
+// SetBlockSize sets the largest size of a block of RecordFormatVB, including its
+// BDW. The default, and largest, block size is 32760.
+func (e *Encoder) SetBlockSize(n int) {
+	if n > 8 && n <= maxBlockSize {
+		e.blockSize = n
+	}
+}
+
+// writeVariable writes record with an RDW, adding it to the current block if the
+// records are blocked.
+func (e *Encoder) writeVariable(record []byte) error {
+	blockSize := e.blockSize
+	if blockSize == 0 {
+		blockSize = maxBlockSize
+	}
+	max := blockSize - 4
+	if e.recordFormat == RecordFormatVB {
+		max -= 4
+	}
+	if len(record) > max {
+		return &OverflowError{Width: max, Len: len(record)}
+	}
+
+	rdw := make([]byte, 4, 4+len(record))
+	binary.BigEndian.PutUint16(rdw, uint16(4+len(record)))
+	record = append(rdw, record...)
+	if e.recordFormat != RecordFormatVB {
+		_, err := e.w.Write(record)
+		return err
+	}
+
+	if len(e.block) > 0 && 4+len(e.block)+len(record) > blockSize {
+		if err := e.flushBlock(); err != nil {
+			return err
+		}
+	}
+	e.block = append(e.block, record...)
+	return nil
+}
+
+// Flush writes the current, partly filled block of RecordFormatVB. Other record
+// formats are written by each call to Encode, and Flush does nothing.
+func (e *Encoder) Flush() error {
+	if err := e.flushBlock(); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// flushBlock writes the current block with a BDW.
+func (e *Encoder) flushBlock() error {
+	if len(e.block) == 0 {
+		return nil
+	}
+	var bdw [4]byte
+	binary.BigEndian.PutUint16(bdw[:], uint16(4+len(e.block)))
+	if _, err := e.w.Write(bdw[:]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(e.block)
+	e.block = e.block[:0]
+	return err
+}
@@ -0,0 +1,188 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRecordFormat(t *testing.T) {
+	type H struct {
+		Code string `fixed:"1,2"`
+		Name string `fixed:"3,7"`
+	}
+	records := []H{{"01", "foo"}, {"02", "\n"}, {"03", "bazqu"}}
+
+	for _, tt := range []struct {
+		name      string
+		format    RecordFormat
+		blockSize int
+		data      []byte
+	}{
+		{
+			name:   "V",
+			format: RecordFormatV,
+			data: []byte("\x00\x0B\x00\x0001foo  " +
+				"\x00\x0B\x00\x0002\n    " +
+				"\x00\x0B\x00\x0003bazqu"),
+		},
+		{
+			name:   "VB",
+			format: RecordFormatVB,
+			data: []byte("\x00\x25\x00\x00" +
+				"\x00\x0B\x00\x0001foo  " +
+				"\x00\x0B\x00\x0002\n    " +
+				"\x00\x0B\x00\x0003bazqu"),
+		},
+		{
+			name:      "VB with small blocks",
+			format:    RecordFormatVB,
+			blockSize: 26,
+			data: []byte("\x00\x1A\x00\x00" +
+				"\x00\x0B\x00\x0001foo  " +
+				"\x00\x0B\x00\x0002\n    " +
+				"\x00\x0F\x00\x00" +
+				"\x00\x0B\x00\x0003bazqu"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.data))
+			dec.SetRecordFormat(tt.format)
+			var have []H
+			if err := dec.Decode(&have); err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(have, records) {
+				t.Errorf("Decode() want %q, have %q", records, have)
+			}
+
+			buff := new(bytes.Buffer)
+			enc := NewEncoder(buff)
+			enc.SetRecordFormat(tt.format)
+			enc.SetBlockSize(tt.blockSize)
+			if err := enc.Encode(records); err != nil {
+				t.Fatalf("Encode() unexpected error: %v", err)
+			}
+			if err := enc.Flush(); err != nil {
+				t.Fatalf("Flush() unexpected error: %v", err)
+			}
+			if !bytes.Equal(buff.Bytes(), tt.data) {
+				t.Errorf("Encode() want %q, have %q", tt.data, buff.Bytes())
+			}
+		})
+	}
+}
+
+func TestRecordFormat_VB(t *testing.T) {
+	type H struct {
+		Code string `fixed:"1,2"`
+	}
+
+	t.Run("extended BDW and padding", func(t *testing.T) {
+		data := []byte("\x80\x00\x00\x10" +
+			"\x00\x06\x00\x0001" +
+			"\x00\x00\x00\x00\x00\x00" +
+			"\x00\x0A\x00\x00" +
+			"\x00\x06\x00\x0002")
+		dec := NewDecoder(bytes.NewReader(data))
+		dec.SetRecordFormat(RecordFormatVB)
+		var have []H
+		if err := dec.Decode(&have); err != nil {
+			t.Fatalf("Decode() unexpected error: %v", err)
+		}
+		if want := []H{{"01"}, {"02"}}; !reflect.DeepEqual(have, want) {
+			t.Errorf("Decode() want %v, have %v", want, have)
+		}
+	})
+
+	for _, tt := range []struct {
+		name   string
+		format RecordFormat
+		data   string
+		offset int64
+	}{
+		{"short RDW", RecordFormatV, "\x00\x02\x00\x00", 0},
+		{"spanned record", RecordFormatV, "\x00\x06\x00\x0001\x00\x06\x01\x0002", 6},
+		{"short BDW", RecordFormatVB, "\x00\x04\x00\x00", 0},
+		{"reserved BDW bytes", RecordFormatVB, "\x00\x0A\x00\x01\x00\x06\x00\x0001", 0},
+		{"record exceeds block", RecordFormatVB, "\x00\x0A\x00\x00\x00\x08\x00\x0001", 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader([]byte(tt.data)))
+			dec.SetRecordFormat(tt.format)
+			var have []H
+			err := dec.Decode(&have)
+			var dwErr *DescriptorWordError
+			if !errors.As(err, &dwErr) || dwErr.Offset != tt.offset {
+				t.Errorf("Decode() want *DescriptorWordError at offset %d, have %v", tt.offset, err)
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, tt := range []struct {
+			format RecordFormat
+			data   string
+		}{
+			{RecordFormatV, "\x00\x06\x00\x000"},
+			{RecordFormatVB, "\x00\x0A\x00\x00\x00\x06\x00\x0001\x00\x0A"},
+			{RecordFormatVB, "\x00\x0E\x00\x00\x00\x06\x00\x0001"},
+		} {
+			dec := NewDecoder(bytes.NewReader([]byte(tt.data)))
+			dec.SetRecordFormat(tt.format)
+			var have []H
+			err := dec.Decode(&have)
+			var truncErr *TruncatedRecordError
+			if !errors.As(err, &truncErr) {
+				t.Errorf("Decode(%q) want *TruncatedRecordError, have %v", tt.data, err)
+			}
+		}
+	})
+
+	t.Run("one record per call", func(t *testing.T) {
+		buff := new(bytes.Buffer)
+		enc := NewEncoder(buff)
+		enc.SetRecordFormat(RecordFormatVB)
+		enc.SetBlockSize(16)
+		for _, h := range []H{{"01"}, {"02"}, {"03"}} {
+			if err := enc.Encode(h); err != nil {
+				t.Fatalf("Encode() unexpected error: %v", err)
+			}
+		}
+		// The first block is written once it is full, and the last one by Flush.
+		want := "\x00\x10\x00\x00" +
+			"\x00\x06\x00\x0001" +
+			"\x00\x06\x00\x0002"
+		if buff.String() != want {
+			t.Errorf("Encode() want %q, have %q", want, buff.String())
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatalf("Flush() unexpected error: %v", err)
+		}
+		want += "\x00\x0A\x00\x00" +
+			"\x00\x06\x00\x0003"
+		if buff.String() != want {
+			t.Errorf("Flush() want %q, have %q", want, buff.String())
+		}
+	})
+
+	t.Run("record too long", func(t *testing.T) {
+		enc := NewEncoder(new(bytes.Buffer))
+		enc.SetRecordFormat(RecordFormatVB)
+		enc.SetBlockSize(9)
+		err := enc.Encode(H{"01"})
+		var overflowErr *OverflowError
+		if !errors.As(err, &overflowErr) || overflowErr.Width != 1 {
+			t.Errorf("Encode() want *OverflowError, have %v", err)
+		}
+
+		// Block sizes that cannot hold a record are ignored.
+		enc = NewEncoder(new(bytes.Buffer))
+		enc.SetRecordFormat(RecordFormatVB)
+		enc.SetBlockSize(8)
+		if err := enc.Encode(H{"01"}); err != nil {
+			t.Errorf("Encode() want block size to be ignored, have %v", err)
+		}
+	})
+}
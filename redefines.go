@@ -0,0 +1,90 @@
+package fixedwidth
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// A Selector is implemented by structs that select themselves which of their
+// alternative fields with the when=func tag option are active, e.g. from the values of
+// several other fields.
+type Selector interface {
+	// Selected reports whether the alternative field named field is decoded. It is
+	// called after the fields that are not alternatives have been decoded.
+	Selected(field string) bool
+}
+
+var selectorType = reflect.TypeOf((*Selector)(nil)).Elem()
+
+// whenSelector is the value of the when tag option of fields selected by a Selector.
+const whenSelector = "func"
+
+// whenSpec makes a field one of several alternatives for the same interval (COBOL
+// REDEFINES). An alternative is active if the text of a selector field is one of the
+// given values, or if the struct is a Selector that selects it.
+type whenSpec struct {
+	name   string // name of the alternative
+	field  string // name of the selector field, or "" for a Selector
+	index  int    // index of the selector field
+	values []string
+}
+
+// newWhenSpec returns the whenSpec of the field sf of the struct t given the value of
+// its when tag option, e.g. "PaymentType:C" or "PaymentType:C|D".
+func newWhenSpec(t reflect.Type, sf reflect.StructField, when string) (*whenSpec, error) {
+	w := &whenSpec{name: sf.Name, index: -1}
+	if when == whenSelector {
+		if !reflect.PtrTo(t).Implements(selectorType) {
+			return nil, errors.New("fixedwidth: " + t.String() + " does not implement Selector")
+		}
+		return w, nil
+	}
+
+	i := strings.IndexByte(when, ':')
+	if i <= 0 {
+		return nil, errors.New("fixedwidth: invalid when option " + when)
+	}
+	w.field, w.values = when[:i], strings.Split(when[i+1:], "|")
+	if f, ok := t.FieldByName(w.field); ok && len(f.Index) == 1 {
+		w.index = f.Index[0]
+	}
+	return w, nil
+}
+
+// active reports whether the alternative is active in line, a line of the struct ss
+// that has been decoded into sv, except for its alternatives.
+func (w *whenSpec) active(ss structSpec, sv reflect.Value, line rawValue) (bool, error) {
+	if w.field == "" {
+		if sv.CanAddr() {
+			sv = sv.Addr()
+		}
+		s, ok := sv.Interface().(Selector)
+		if !ok {
+			return false, errors.New("fixedwidth: " + sv.Type().String() + " does not implement Selector")
+		}
+		return s.Selected(w.name), nil
+	}
+	if w.index < 0 || !ss.fieldSpecs[w.index].ok || ss.fieldSpecs[w.index].when != nil {
+		return false, errors.New("fixedwidth: selector field " + w.field + " not found")
+	}
+	spec := ss.fieldSpecs[w.index]
+	value := rawValueFromLine(line, spec.startPos, spec.endPos, spec.format).data
+	for _, v := range w.values {
+		if value == v {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// conflictingAlternativesError reports that two alternatives of the struct t for
+// overlapping intervals are both set.
+func conflictingAlternativesError(t reflect.Type, a, b *whenSpec) error {
+	return errors.New("fixedwidth: alternatives " + a.name + " and " + b.name + " of " + t.String() + " are both set")
+}
+
+// overlaps reports whether the intervals of two field specs overlap.
+func (s fieldSpec) overlaps(o fieldSpec) bool {
+	return s.startPos <= o.endPos && o.startPos <= s.endPos
+}
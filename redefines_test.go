@@ -0,0 +1,104 @@
+package fixedwidth
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type redefinesCard struct {
+	Number string `fixed:"1,16"`
+	Expiry string `fixed:"17,20"`
+}
+
+type redefinesBank struct {
+	Routing string `fixed:"1,9"`
+	Account string `fixed:"10,20"`
+}
+
+type redefinesSelector struct {
+	Kind   int            `fixed:"1,1"`
+	Card   *redefinesCard `fixed:"2,21,none,when=func"`
+	Bank   *redefinesBank `fixed:"2,21,none,when=func"`
+	Amount int            `fixed:"22,25"`
+}
+
+func (s *redefinesSelector) Selected(field string) bool {
+	return field == "Card" && s.Kind == 1 || field == "Bank" && s.Kind == 2
+}
+
+func TestRedefines(t *testing.T) {
+	type H struct {
+		PaymentType string         `fixed:"1,1"`
+		Card        *redefinesCard `fixed:"2,21,none,when=PaymentType:C|D"`
+		Bank        *redefinesBank `fixed:"2,21,none,when=PaymentType:B"`
+		Amount      int            `fixed:"22,25"`
+	}
+
+	for _, tt := range []struct {
+		name string
+		data string
+		want H
+	}{
+		{"card", "C4111111111111111122542  ", H{"C", &redefinesCard{"4111111111111111", "1225"}, nil, 42}},
+		{"debit", "D4111111111111111122542  ", H{"D", &redefinesCard{"4111111111111111", "1225"}, nil, 42}},
+		{"bank", "B0210000211234567890142  ", H{"B", nil, &redefinesBank{"021000021", "12345678901"}, 42}},
+		{"neither", "X                    42  ", H{"X", nil, nil, 42}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// Inactive alternatives are reset.
+			have := H{Card: &redefinesCard{}, Bank: &redefinesBank{}}
+			if err := Unmarshal([]byte(tt.data), &have); err != nil {
+				t.Fatalf("Unmarshal(%q) unexpected error: %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("Unmarshal(%q) want %+v, have %+v", tt.data, tt.want, have)
+			}
+
+			o, err := Marshal(tt.want)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+			if string(o) != tt.data {
+				t.Errorf("Marshal() want %q, have %q", tt.data, o)
+			}
+		})
+	}
+
+	t.Run("selector", func(t *testing.T) {
+		var have redefinesSelector
+		data := "20210000211234567890142  "
+		if err := Unmarshal([]byte(data), &have); err != nil {
+			t.Fatalf("Unmarshal(%q) unexpected error: %v", data, err)
+		}
+		want := redefinesSelector{2, nil, &redefinesBank{"021000021", "12345678901"}, 42}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("Unmarshal(%q) want %+v, have %+v", data, want, have)
+		}
+	})
+
+	t.Run("both set", func(t *testing.T) {
+		_, err := Marshal(H{Card: &redefinesCard{}, Bank: &redefinesBank{}})
+		if err == nil || !strings.Contains(err.Error(), "both set") {
+			t.Errorf("Marshal() want error, have %v", err)
+		}
+	})
+}
+
+func TestRedefines_invalid(t *testing.T) {
+	for _, v := range []interface{}{
+		&struct {
+			Card *redefinesCard `fixed:"1,20,when=Missing:C"`
+		}{},
+		&struct {
+			Card *redefinesCard `fixed:"1,20,when=func"`
+		}{},
+		&struct {
+			Card *redefinesCard `fixed:"1,20,when=C"`
+		}{},
+	} {
+		if err := Unmarshal([]byte("C"), v); err == nil {
+			t.Errorf("Unmarshal(%T) want error", v)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package fixedwidth
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// A Range is an inclusive interval of positions within a line. Positions start at 1.
+type Range struct {
+	Start, End int
+}
+
+// A Registry maps discriminator values to the Go types that lines should be decoded
+// into. It allows a single stream to contain several record layouts.
+//
+// The discriminator of a line is the concatenation of the values found at each of the
+// registry's ranges, in order. For example, with the ranges {1,2} and {6,6}, the line
+// "0500000..." has the discriminator "050".
+type Registry struct {
+	ranges   []Range
+	types    map[string]reflect.Type
+	fallback reflect.Type
+	err      error
+}
+
+// NewRegistry returns a new registry that discriminates lines using the values at the
+// given ranges. Ranges that are out of order or start before position 1 are reported
+// when the registry is used to decode a line.
+func NewRegistry(ranges ...Range) *Registry {
+	r := &Registry{
+		ranges: ranges,
+		types:  make(map[string]reflect.Type),
+	}
+	for _, rng := range ranges {
+		if rng.Start < 1 || rng.End < rng.Start {
+			r.err = fmt.Errorf("fixedwidth: registry has invalid range %d-%d", rng.Start, rng.End)
+			break
+		}
+	}
+	return r
+}
+
+// Register maps a discriminator value to the type of v. v may be a value or a pointer,
+// e.g. VISA_TC05_TCR0{} or (*VISA_TC05_TCR0)(nil). Lines with the discriminator are
+// decoded into a new value of the type and stored as a pointer.
+func (r *Registry) Register(key string, v interface{}) {
+	r.types[key] = indirectType(reflect.TypeOf(v))
+}
+
+// SetFallback sets the type used to decode lines with a discriminator that has not
+// been registered. If v is nil (the default), an *UnknownRecordError is returned for
+// such lines instead.
+//
+// A fallback of string, e.g. SetFallback(""), stores the raw line as a *string.
+func (r *Registry) SetFallback(v interface{}) {
+	if v == nil {
+		r.fallback = nil
+		return
+	}
+	r.fallback = indirectType(reflect.TypeOf(v))
+}
+
+// lookup returns the type registered for the discriminator of raw.
+func (r *Registry) lookup(raw rawValue) (reflect.Type, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var key string
+	for _, rng := range r.ranges {
+		key += rawValueFromLine(raw, rng.Start, rng.End, format{alignment: alignmentNone}).data
+	}
+	if t, ok := r.types[key]; ok {
+		return t, nil
+	}
+	if r.fallback != nil {
+		return r.fallback, nil
+	}
+	return nil, &UnknownRecordError{Key: key}
+}
+
+// An UnknownRecordError describes a line with a discriminator that has no type
+// registered in the Decoder's Registry.
+type UnknownRecordError struct {
+	Key string // the discriminator of the line
+}
+
+func (e *UnknownRecordError) Error() string {
+	return "fixedwidth: no type registered for discriminator " + strconv.Quote(e.Key)
+}
+
+// SetRegistry configures `Decoder` to choose the type of each line from r when
+// decoding into an interface value, e.g. an interface{} or a []interface{}. Each line
+// is stored as a pointer to the registered type, which can be recovered with a type
+// switch.
+func (d *Decoder) SetRegistry(r *Registry) {
+	d.registry = r
+}
+
+// registeredSetter decodes raw into a new value of the type registered for it and
+// stores a pointer to the value in v, which must be an interface.
+func (d *Decoder) registeredSetter(v reflect.Value, raw rawValue) error {
+	t, err := d.registry.lookup(raw)
+	if err != nil {
+		return err
+	}
+	nv := reflect.New(t)
+	if !nv.Type().AssignableTo(v.Type()) {
+		return &UnmarshalTypeError{Value: raw.data, Type: v.Type()}
+	}
+	if err := newValueSetter(t, d.config)(nv.Elem(), raw); err != nil {
+		return err
+	}
+	v.Set(nv)
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
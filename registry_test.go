@@ -0,0 +1,117 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readVISAFixtures(t *testing.T) []byte {
+	t.Helper()
+	tcr0, err := os.ReadFile("./visa_tc05_tcr0_test.hex")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	tcr1, err := os.ReadFile("./visa_tc05_tcr1_test.hex")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	return bytes.Join([][]byte{tcr0, tcr1}, []byte("\n"))
+}
+
+func TestDecoder_SetRegistry(t *testing.T) {
+	registry := NewRegistry(Range{1, 2}, Range{6, 6})
+	registry.Register("250", VISA_TC05_TCR0{})
+	registry.Register("251", (*VISA_TC05_TCR1)(nil))
+
+	t.Run("slice", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(readVISAFixtures(t)))
+		dec.SetRegistry(registry)
+
+		var records []interface{}
+		if err := dec.Decode(&records); err != nil {
+			t.Fatalf("Decode() unexpected error: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("Decode() want 2 records, have %d", len(records))
+		}
+		if r, ok := records[0].(*VISA_TC05_TCR0); !ok || r.AccountNumber != 4830970000162705 {
+			t.Errorf("Decode() record 0 want *VISA_TC05_TCR0, have %#v", records[0])
+		}
+		if r, ok := records[1].(*VISA_TC05_TCR1); !ok || r.TransactionComponentSequenceNumber != 1 {
+			t.Errorf("Decode() record 1 want *VISA_TC05_TCR1, have %#v", records[1])
+		}
+	})
+
+	t.Run("incremental", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(readVISAFixtures(t)))
+		dec.SetRegistry(registry)
+
+		var types []string
+		for {
+			var record interface{}
+			err := dec.Decode(&record)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			switch record.(type) {
+			case *VISA_TC05_TCR0:
+				types = append(types, "TCR0")
+			case *VISA_TC05_TCR1:
+				types = append(types, "TCR1")
+			}
+		}
+		if len(types) != 2 || types[0] != "TCR0" || types[1] != "TCR1" {
+			t.Errorf("Decode() want [TCR0 TCR1], have %v", types)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte("990000")))
+		dec.SetRegistry(registry)
+
+		var record interface{}
+		err := dec.Decode(&record)
+		var unknownErr *UnknownRecordError
+		if !errors.As(err, &unknownErr) || unknownErr.Key != "990" {
+			t.Errorf("Decode() want *UnknownRecordError for \"990\", have %v", err)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		for _, rng := range []Range{{0, 1}, {3, 1}} {
+			invalid := NewRegistry(rng)
+			invalid.SetFallback("")
+
+			dec := NewDecoder(bytes.NewReader([]byte("990000")))
+			dec.SetRegistry(invalid)
+
+			var record interface{}
+			if err := dec.Decode(&record); err == nil || !strings.Contains(err.Error(), "invalid range") {
+				t.Errorf("Decode() want an invalid range error for %v, have %v", rng, err)
+			}
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		fallback := NewRegistry(Range{1, 2})
+		fallback.SetFallback("")
+
+		dec := NewDecoder(bytes.NewReader([]byte("99000X")))
+		dec.SetRegistry(fallback)
+
+		var record interface{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("Decode() unexpected error: %v", err)
+		}
+		if s, ok := record.(*string); !ok || *s != "99000X" {
+			t.Errorf("Decode() want *string \"99000X\", have %#v", record)
+		}
+	})
+}
@@ -1,121 +1,574 @@
 package fixedwidth
 
 import (
+	"errors"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 )
 
-// parseTagWithFormat splits a struct fields fixed tag into its start position, end
-// position, format, and padding character.
+// parseTag splits a struct fields fixed tag into its start position, end
+// position, and format.
 //
 // If the tag is not valid, ok will be false.
 func parseTag(tag string) (startPos, endPos int, format format, ok bool) {
-	parts := strings.Split(tag, ",")
-	if len(parts) < 2 || len(parts) > 4 {
-		return 0, 0, defaultFormat, false
-	}
+	startPos, endPos, format, _, ok = parseTagWithOptions(tag)
+	return startPos, endPos, format, ok
+}
 
-	var err error
-	if startPos, err = strconv.Atoi(parts[0]); err != nil {
-		return 0, 0, defaultFormat, false
+// parseTagWithOptions is like parseTag but also returns the options that may follow
+// the positional arguments of the tag, e.g. `fixed:"1,10,right,0,truncate"`.
+//
+// Options are either a bare name or a name=value pair. If any option is unknown or
+// malformed, ok will be false.
+//
+// The positions of a field may also be given relative to the end of the previous
+// field, e.g. `fixed:"+1,+10"`, or by its length alone, e.g. `fixed:"len=10,right"`.
+// The positions of such tags are returned relative to the end of the previous field,
+// after skipping opts.skip characters, and opts.relative is true.
+func parseTagWithOptions(tag string) (startPos, endPos int, format format, opts fieldOptions, ok bool) {
+	startPos, endPos, format, opts, ok, _ = parseFieldTag(tag)
+	return startPos, endPos, format, opts, ok
+}
 
-	}
-	if endPos, err = strconv.Atoi(parts[1]); err != nil {
-		return 0, 0, defaultFormat, false
+// parseFieldTag is like parseTagWithOptions but also returns an error describing the
+// options of a tag that are unknown, malformed or not valid with its positions.
+// Tags with invalid positions are not reported, as they do not describe a field.
+func parseFieldTag(tag string) (startPos, endPos int, format format, opts fieldOptions, ok bool, err error) {
+	parts := splitTag(tag)
 
-	}
-	if startPos > endPos || (startPos == 0 && endPos == 0) {
-		return 0, 0, defaultFormat, false
+	var rest []string
+	switch {
+	case strings.HasPrefix(parts[0], "+"):
+		if len(parts) < 2 || !strings.HasPrefix(parts[1], "+") {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
+		}
+		if startPos, err = strconv.Atoi(parts[0][1:]); err != nil {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
+		}
+		if endPos, err = strconv.Atoi(parts[1][1:]); err != nil {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
+		}
+		if startPos < 1 || startPos > endPos {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
+		}
+		opts.relative = true
+		rest = parts[2:]
+
+	case strings.Contains(parts[0], "="):
+		// The positions are given by the len option.
+		opts.relative = true
+		rest = parts
+
+	default:
+		if len(parts) < 2 {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
+		}
+		if startPos, err = strconv.Atoi(parts[0]); err != nil {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
 
+		}
+		if endPos, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
+
+		}
+		if startPos > endPos || (startPos == 0 && endPos == 0) {
+			return 0, 0, defaultFormat, fieldOptions{}, false, nil
+
+		}
+		rest = parts[2:]
 	}
 
 	format = defaultFormat
 
-	if len(parts) >= 3 {
-		alignment := alignment(parts[2])
+	var positional []string
+	for _, part := range rest {
+		if name, value, isOption := splitOption(part); isOption {
+			if !opts.set(name, value) {
+				return 0, 0, defaultFormat, fieldOptions{}, false, errors.New("an invalid option " + strconv.Quote(part))
+			}
+			continue
+		}
+		positional = append(positional, part)
+	}
+	if len(positional) > 2 {
+		return 0, 0, defaultFormat, fieldOptions{}, false, nil
+	}
+	switch {
+	case opts.length > 0 && (!opts.relative || startPos != 0):
+		// The length is only valid on its own.
+		return 0, 0, defaultFormat, fieldOptions{}, false, errors.New("a len option with positions")
+	case opts.length > 0:
+		startPos, endPos = 1, opts.length
+	case opts.relative && startPos == 0:
+		return 0, 0, defaultFormat, fieldOptions{}, false, nil
+	case opts.skip > 0 && !opts.relative:
+		return 0, 0, defaultFormat, fieldOptions{}, false, errors.New("a skip option with absolute positions")
+	}
+
+	if len(positional) >= 1 {
+		alignment := alignment(positional[0])
 		if alignment.Valid() {
 			format.alignment = alignment
 		}
 	}
 
-	if len(parts) >= 4 {
-		v := parts[3]
+	if len(positional) >= 2 {
+		v := positional[1]
 		switch {
 		case v == "_":
 			format.padChar = ' '
-		case parts[3] == "__":
+		case v == "__":
 			format.padChar = '_'
 		case len(v) > 0:
 			format.padChar = v[0]
 		}
 	}
 
-	return startPos, endPos, format, true
+	return startPos, endPos, format, opts, true, nil
+}
+
+// splitTag splits a tag into its comma separated parts. A comma or backslash preceded
+// by a backslash is part of the value, so that e.g. a field is padded with commas by
+// `fixed:"1,10,right,\\,"`.
+func splitTag(tag string) []string {
+	if !strings.Contains(tag, `\`) {
+		return strings.Split(tag, ",")
+	}
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch c := tag[i]; {
+		case c == '\\' && i+1 < len(tag) && (tag[i+1] == ',' || tag[i+1] == '\\'):
+			i++
+			part.WriteByte(tag[i])
+		case c == ',':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(c)
+		}
+	}
+	return append(parts, part.String())
+}
+
+// fieldOptions holds the options that may follow the positional arguments of a
+// fixed tag.
+type fieldOptions struct {
+	// truncate allows an overflowing value to be truncated even in strict mode.
+	truncate bool
+
+	// record marks the field as a sub-record of a composite struct. The field is
+	// decoded from a line of its own; the line belongs to the field when the value at
+	// the tag's interval equals record.
+	record   string
+	isRecord bool
+
+	// codePage is the name of the code page the field is encoded in.
+	codePage string
+
+	// comp3 encodes the field as a packed decimal.
+	comp3 bool
+
+	// zoned encodes the field as a zoned decimal with an overpunched sign.
+	zoned   zonedStyle
+	isZoned bool
+
+	// binary encodes the field as a binary integer.
+	binary   binaryStyle
+	isBinary bool
+
+	// scale is the number of implied decimal places of a numeric field. A numeric
+	// field with a scale and no other encoding is a display decimal.
+	scale    int
+	hasScale bool
+
+	// sign is the sign style of a display decimal or formatted number.
+	sign    signStyle
+	hasSign bool
+
+	// prec is the number of digits after the decimal point of a formatted number.
+	prec    int
+	hasPrec bool
+
+	// round is the rounding mode of a numeric field.
+	round    roundingMode
+	hasRound bool
+
+	// currency is the name of the field that holds the currency code of an amount.
+	currency string
+
+	// time is the layout or preset of a time field, and tz its time zone.
+	time string
+	tz   string
+
+	// ref is the name of the field that holds the reference date of a partial date.
+	ref string
+
+	// bytes is the encoding of a []byte or [N]byte field.
+	bytes bytesEncoding
+
+	// occurs is the number of elements of a repeating group, and depending the name
+	// of the field that holds the number of elements of a variable-count group.
+	occurs    int
+	depending string
+
+	// relative reports whether the positions of the tag are relative to the end of
+	// the previous field, after skipping skip characters. length is the length of a
+	// field positioned by the len option.
+	relative bool
+	length   int
+	skip     int
+
+	// when makes the field an alternative for its interval. It is the selector field
+	// and its values, e.g. "PaymentType:C|D", or "func" for a Selector.
+	when string
+}
+
+// optionFlags are the options that are valid without a value.
+var optionFlags = map[string]bool{
+	"truncate":  true,
+	"comp3":     true,
+	"overpunch": true,
+	"zoned":     true,
+	"binary":    true,
+	"hex":       true,
+	"base64":    true,
+}
+
+// splitOption reports whether a tag part is an option rather than a positional
+// argument and splits it into its name and value.
+func splitOption(part string) (name, value string, ok bool) {
+	if i := strings.IndexByte(part, '='); i > 0 {
+		return part[:i], part[i+1:], true
+	}
+	if optionFlags[part] {
+		return part, "", true
+	}
+	return "", "", false
+}
+
+// set applies a single option to opts. False is returned if the option is unknown
+// or its value is invalid.
+func (opts *fieldOptions) set(name, value string) bool {
+	switch name {
+	case "truncate":
+		opts.truncate = true
+		return value == ""
+	case "record":
+		opts.record, opts.isRecord = value, true
+		return value != ""
+	case "codepage":
+		opts.codePage = value
+		return value != ""
+	case "comp3":
+		opts.comp3 = true
+		return value == ""
+	case "overpunch", "zoned":
+		opts.zoned = zonedStyle{ebcdic: name == "zoned", leading: value == "leading"}
+		opts.isZoned = true
+		return value == "" || value == "leading" || value == "trailing"
+	case "binary":
+		opts.binary = binaryStyle{littleEndian: value == "le"}
+		opts.isBinary = true
+		return value == "" || value == "be" || value == "le"
+	case "scale":
+		scale, err := strconv.Atoi(value)
+		opts.scale, opts.hasScale = scale, true
+		return err == nil && scale >= 0
+	case "currency":
+		opts.currency = value
+		return value != ""
+	case "time":
+		opts.time = value
+		return value != ""
+	case "tz":
+		opts.tz = value
+		return value != ""
+	case "ref":
+		opts.ref = value
+		return value != ""
+	case "occurs":
+		occurs, err := strconv.Atoi(value)
+		opts.occurs = occurs
+		return err == nil && occurs > 0
+	case "depending":
+		opts.depending = value
+		return value != ""
+	case "len":
+		length, err := strconv.Atoi(value)
+		opts.length = length
+		return err == nil && length > 0
+	case "skip":
+		skip, err := strconv.Atoi(value)
+		opts.skip = skip
+		return err == nil && skip >= 0
+	case "when":
+		opts.when = value
+		return value != ""
+	case "hex":
+		opts.bytes = bytesHex
+		return value == ""
+	case "base64":
+		opts.bytes = bytesBase64
+		return value == ""
+	case "sign":
+		sign, ok := signStyles[value]
+		opts.sign, opts.hasSign = sign, true
+		return ok
+	case "prec":
+		prec, err := strconv.Atoi(value)
+		opts.prec, opts.hasPrec = prec, true
+		return err == nil && prec >= 0
+	case "round":
+		mode, ok := roundingModes[value]
+		opts.round, opts.hasRound = mode, true
+		return ok
+	}
+	return false
 }
 
 type structSpec struct {
 	// ll is the line length for the struct
 	ll         int
 	fieldSpecs []fieldSpec
+
+	// records holds the sub-records of a composite struct in declaration order.
+	records []recordSpec
+
+	// groups holds the indices of the variable-count groups ordered by position.
+	groups []int
+
+	// alternatives holds the indices of the fields with a when option.
+	alternatives []int
+
+	// err reports fields whose positions cannot be resolved. It is returned when the
+	// struct is encoded or decoded.
+	err error
+}
+
+// recordSpec describes a field of a composite struct that is decoded from, and
+// encoded to, a line of its own.
+type recordSpec struct {
+	index            int
+	startPos, endPos int
+	key              string
+	setter           valueSetter
 }
 
 type fieldSpec struct {
 	startPos, endPos int
 	encoder          valueEncoder
-	codepointEncoder valueEncoder
 	setter           valueSetter
 	format           format
-	ok               bool
+	// truncate reports whether a value longer than the interval may be truncated
+	// when encoding. It is false for fields encoded in strict mode unless the
+	// truncate option is set.
+	truncate bool
+	ok       bool
+
+	// currency links an amount field to its currency field. The field is encoded and
+	// decoded with the encoder and setter returned by currency instead.
+	currency *currencySpec
+
+	// reference links a partial date field to its reference date field. The field is
+	// decoded with the setter returned by reference instead.
+	reference *dateReference
+
+	// depending makes the field a variable-count group. The positions of the fields
+	// of a line are given by structSpec.forLine.
+	depending *dependingSpec
+
+	// when makes the field one of several alternatives for its interval. The field is
+	// only decoded if it is active, and only encoded if it is set.
+	when *whenSpec
 }
 
 func (s fieldSpec) len() int {
 	return s.endPos - s.startPos + 1
 }
 
-func (s fieldSpec) getEncoder(useCodepointIndices bool) valueEncoder {
-	if useCodepointIndices {
-		return s.codepointEncoder
-	}
-	return s.encoder
+// codecConfig holds the Encoder and Decoder settings that change how individual
+// fields are encoded or decoded. Struct specs are built and cached per type and
+// config.
+type codecConfig struct {
+	useCodepointIndices bool
+	strict              bool
+
+	// saturate clamps integers that do not fit their type when decoding.
+	saturate bool
+
+	// codePage is the code page of the whole stream. When set, values are decoded
+	// from, and encoded to, translated text.
+	codePage *CodePage
 }
 
-func buildStructSpec(t reflect.Type) structSpec {
+func buildStructSpec(t reflect.Type, c codecConfig) structSpec {
 	ss := structSpec{
 		fieldSpecs: make([]fieldSpec, t.NumField()),
 	}
+	// prev is the previous field, that relative positions are relative to.
+	var prev struct {
+		name     string
+		endPos   int
+		relative bool
+	}
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 
-		startPos, endPos, format, ok := parseTag(f.Tag.Get("fixed"))
+		startPos, endPos, format, opts, ok, err := parseFieldTag(f.Tag.Get("fixed"))
+		if err != nil && ss.err == nil {
+			// A field with a bad option would otherwise be dropped silently.
+			ss.err = errors.New("fixedwidth: field " + f.Name + " has " + err.Error())
+		}
 		if !ok {
 			continue
 		}
 
+		if opts.relative && !opts.isRecord {
+			offset := prev.endPos + opts.skip
+			startPos, endPos = startPos+offset, endPos+offset
+		}
+		switch {
+		case ss.err != nil:
+		case opts.relative && opts.isRecord:
+			ss.err = errors.New("fixedwidth: sub-record " + f.Name + " must have absolute positions")
+		case !opts.relative && prev.relative && startPos <= prev.endPos:
+			// Absolute positions may not overlap fields that move with the fields before
+			// them.
+			ss.err = errors.New("fixedwidth: field " + f.Name + " at " + strconv.Itoa(startPos) +
+				" overlaps the relatively positioned field " + prev.name + " ending at " + strconv.Itoa(prev.endPos))
+		}
+
+		if opts.isRecord {
+			ss.records = append(ss.records, recordSpec{
+				index:    i,
+				startPos: startPos,
+				endPos:   endPos,
+				key:      opts.record,
+				setter:   newValueSetter(f.Type, c),
+			})
+			continue
+		}
+
+		prev.name, prev.endPos, prev.relative = f.Name, endPos, opts.relative
+
 		ss.fieldSpecs[i].startPos = startPos
 		ss.fieldSpecs[i].endPos = endPos
 		ss.fieldSpecs[i].format = format
+		ss.fieldSpecs[i].truncate = !c.strict || opts.truncate
 		ss.fieldSpecs[i].ok = ok
 
 		if ss.fieldSpecs[i].endPos > ss.ll {
 			ss.ll = ss.fieldSpecs[i].endPos
 		}
 
-		ss.fieldSpecs[i].encoder = newValueEncoder(f.Type, false)
-		ss.fieldSpecs[i].codepointEncoder = newValueEncoder(f.Type, true)
-		ss.fieldSpecs[i].setter = newValueSetter(f.Type)
+		if opts.when != "" {
+			w, err := newWhenSpec(t, f, opts.when)
+			if err != nil {
+				ss.fieldSpecs[i].encoder, ss.fieldSpecs[i].setter = errorCodec(err)
+				continue
+			}
+			ss.fieldSpecs[i].when = w
+			ss.alternatives = append(ss.alternatives, i)
+		}
+
+		if opts.currency != "" {
+			ss.fieldSpecs[i].currency = newCurrencySpec(t, &ss.fieldSpecs[i], opts, c)
+			continue
+		}
+		if opts.depending != "" {
+			d, err := newDependingSpec(t, f, &ss.fieldSpecs[i], opts, c)
+			if err != nil {
+				ss.fieldSpecs[i].encoder, ss.fieldSpecs[i].setter = errorCodec(err)
+				continue
+			}
+			ss.fieldSpecs[i].depending = d
+			continue
+		}
+		ss.fieldSpecs[i].encoder, ss.fieldSpecs[i].setter = newFieldCodec(f.Type, &ss.fieldSpecs[i], opts, c)
+		if opts.ref != "" && indirectType(f.Type) == timeType {
+			ss.fieldSpecs[i].reference = newDateReference(t, opts)
+		}
 	}
+	ss.groups = ss.dependingGroups()
 	return ss
 }
 
-var fieldSpecCache sync.Map // map[reflect.Type]structSpec
+// newFieldCodec returns the encoder and setter for a field of type t, taking the
+// options of the field's tag into account. The format of spec may be adjusted to
+// suit the encoding of the field.
+func newFieldCodec(t reflect.Type, spec *fieldSpec, opts fieldOptions, c codecConfig) (valueEncoder, valueSetter) {
+	switch {
+	case opts.occurs > 0:
+		// The options of a repeating group apply to each of its elements.
+		return newGroupCodec(t, spec, opts, c)
+
+	case opts.comp3:
+		// Packed decimals are binary and must never be padded or trimmed.
+		spec.format.alignment = alignmentNone
+		return packedEncoder(t, spec.len(), opts.scale, opts.round, c), packedSetter(opts.scale, c)
+
+	case opts.isZoned:
+		spec.format.alignment = alignmentNone
+		return zonedEncoder(t, opts.zoned, spec.len(), opts.scale, opts.round, c), zonedSetter(opts.zoned, opts.scale, c)
+
+	case opts.isBinary:
+		// Binary integers are padded with zero bytes when nil.
+		spec.format.alignment = alignmentNone
+		spec.format.padChar = 0
+		return binaryEncoder(t, opts.binary, spec.len(), opts.scale, opts.round, c), binarySetter(t, opts.binary, spec.len(), opts.scale, c)
+
+	case isDisplayDecimal(t, opts):
+		spec.format.alignment = alignmentNone
+		s := displayStyle{sign: opts.sign}
+		return displayEncoder(s, spec.len(), opts.scale, opts.round, c), displaySetter(s, opts.scale, c)
+
+	case indirectType(t) == timeType:
+		return newTimeCodec(opts.time, opts.tz)
+
+	case isBytes(t):
+		if opts.bytes == bytesRaw {
+			// Raw bytes are never trimmed, and are padded with zero bytes.
+			spec.format.alignment = alignmentNone
+		}
+		return bytesEncoder(opts.bytes, spec.len(), c), bytesSetter(opts.bytes, c)
+
+	case opts.codePage != "":
+		cp, ok := LookupCodePage(opts.codePage)
+		if !ok {
+			return unknownCodePageEncoder(opts.codePage), unknownCodePageSetter(opts.codePage)
+		}
+		// The padding character is given as text and must be translated as well,
+		// unless the whole stream is translated.
+		if c.codePage == nil {
+			if b, err := cp.Encode(string(rune(spec.format.padChar))); err == nil {
+				spec.format.padChar = b[0]
+			}
+		}
+		return codePageEncoder(t, cp, c), codePageSetter(t, cp, c)
+
+	case isFormattedNumber(t, opts):
+		s := numberStyle{sign: opts.sign, prec: opts.prec, hasPrec: opts.hasPrec, round: opts.round}
+		return numberEncoder(t, s, *spec, c), numberSetter(s, c)
+	}
+	return newValueEncoder(t, c), newValueSetter(t, c)
+}
+
+type structSpecKey struct {
+	t reflect.Type
+	c codecConfig
+}
+
+var fieldSpecCache sync.Map // map[structSpecKey]structSpec
 
 // cachedStructSpec is like buildStructSpec but cached to prevent duplicate work.
-func cachedStructSpec(t reflect.Type) structSpec {
-	if f, ok := fieldSpecCache.Load(t); ok {
+func cachedStructSpec(t reflect.Type, c codecConfig) structSpec {
+	key := structSpecKey{t, c}
+	if f, ok := fieldSpecCache.Load(key); ok {
 		return f.(structSpec)
 	}
-	f, _ := fieldSpecCache.LoadOrStore(t, buildStructSpec(t))
+	f, _ := fieldSpecCache.LoadOrStore(key, buildStructSpec(t, c))
 	return f.(structSpec)
 }
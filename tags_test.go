@@ -1,8 +1,10 @@
 package fixedwidth
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -31,6 +33,26 @@ func TestParseTag(t *testing.T) {
 		{"Space Padding Character (_)", "0,0,default,_", 0, 0, defaultFormat, false},
 		{"Underscore Padding Character (__)", "0,0,default,__", 0, 0, defaultFormat, false},
 		{"Multi-byte Padding Character", "0,0,default,00", 0, 0, defaultFormat, false},
+		{"Valid Tag w/ Option", "1,10,truncate", 1, 10, defaultFormat, true},
+		{"Valid Tag w/ Format and Option", "1,10,right,0,truncate", 1, 10, format{right, '0'}, true},
+		{"Unknown Option", "1,10,foo=bar", 0, 0, defaultFormat, false},
+		{"Flag Option With Value", "1,10,truncate=yes", 0, 0, defaultFormat, false},
+		{"Relative Positions", "+2,+10", 2, 10, defaultFormat, true},
+		{"Relative Positions w/ Format", "+1,+10,right,0", 1, 10, format{right, '0'}, true},
+		{"Length", "len=12", 1, 12, defaultFormat, true},
+		{"Length w/ Format and Skip", "len=12,right,skip=3", 1, 12, format{right, defaultPadChar}, true},
+		{"Relative Start Only", "+2,10", 0, 0, defaultFormat, false},
+		{"Relative Start Zero", "+0,+3", 0, 0, defaultFormat, false},
+		{"Relative Positions w/ Length", "+1,+5,len=5", 0, 0, defaultFormat, false},
+		{"Absolute Positions w/ Length", "1,5,len=5", 0, 0, defaultFormat, false},
+		{"Absolute Positions w/ Skip", "1,5,skip=2", 0, 0, defaultFormat, false},
+		{"Zero Length", "len=0", 0, 0, defaultFormat, false},
+		{"Options Only", "skip=2,truncate", 0, 0, defaultFormat, false},
+		{"Underscore Padding Character", "1,10,left,__", 1, 10, format{left, '_'}, true},
+		{"Escaped Comma Padding Character", "1,10,right,\\,", 1, 10, format{right, ','}, true},
+		{"Escaped Comma Padding Character w/ Option", "1,10,right,\\,,truncate", 1, 10, format{right, ','}, true},
+		{"Escaped Backslash Padding Character", "1,10,left,\\\\", 1, 10, format{left, '\\'}, true},
+		{"Backslash Padding Character", "1,10,left,\\", 1, 10, format{left, '\\'}, true},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			startPos, endPos, format, ok := parseTag(tt.tag)
@@ -72,3 +94,80 @@ func TestFieldSpec_len(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildStructSpec_relative(t *testing.T) {
+	type H struct {
+		A string `fixed:"1,2"`
+		B string `fixed:"len=3"`
+		C int    `fixed:"len=4,right,0,skip=2"`
+		D string `fixed:"+2,+3"`
+		E string `fixed:"16,17"`
+		F string `fixed:"len=1"`
+	}
+
+	ss := buildStructSpec(reflect.TypeOf(H{}), codecConfig{})
+	if ss.err != nil {
+		t.Fatalf("buildStructSpec() unexpected error: %v", ss.err)
+	}
+	want := [][2]int{{1, 2}, {3, 5}, {8, 11}, {13, 14}, {16, 17}, {18, 18}}
+	for i, spec := range ss.fieldSpecs {
+		if have := [2]int{spec.startPos, spec.endPos}; have != want[i] {
+			t.Errorf("field %d want positions %v, have %v", i, want[i], have)
+		}
+	}
+	if ss.ll != 18 {
+		t.Errorf("buildStructSpec() want line length 18, have %d", ss.ll)
+	}
+
+	v := H{"AA", "BBB", 42, "DD", "EE", "F"}
+	o, err := Marshal(v)
+	if want := "AABBB  0042 DD EEF"; err != nil || string(o) != want {
+		t.Errorf("Marshal() want %q, have %q, %v", want, o, err)
+	}
+	var have H
+	if err := Unmarshal(o, &have); err != nil || have != v {
+		t.Errorf("Unmarshal() want %+v, have %+v, %v", v, have, err)
+	}
+
+	t.Run("overlap", func(t *testing.T) {
+		type O struct {
+			A string `fixed:"len=5"`
+			B string `fixed:"5,6"`
+		}
+		var o O
+		if err := Unmarshal([]byte("123456"), &o); err == nil {
+			t.Errorf("Unmarshal() want error for overlapping positions")
+		}
+		if _, err := Marshal(o); err == nil {
+			t.Errorf("Marshal() want error for overlapping positions")
+		}
+	})
+}
+
+func TestBuildStructSpec_invalidOption(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		v    interface{}
+	}{
+		{"unknown option", &struct {
+			A string `fixed:"1,5"`
+			B int    `fixed:"6,10,scael=2"`
+		}{}},
+		{"malformed option", &struct {
+			A int `fixed:"1,5,scale=x"`
+		}{}},
+		{"len with positions", &struct {
+			A string `fixed:"1,5,len=5"`
+		}{}},
+		{"skip with absolute positions", &struct {
+			A string `fixed:"1,5,skip=1"`
+		}{}},
+	} {
+		if err := Unmarshal([]byte("x    12345"), tt.v); err == nil || !strings.Contains(err.Error(), "option") {
+			t.Errorf("%s: Unmarshal() want an invalid option error, have %v", tt.name, err)
+		}
+		if err := NewEncoder(new(bytes.Buffer)).Encode(tt.v); err == nil || !strings.Contains(err.Error(), "option") {
+			t.Errorf("%s: Encode() want an invalid option error, have %v", tt.name, err)
+		}
+	}
+}
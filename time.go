@@ -0,0 +1,294 @@
+package fixedwidth
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeLayouts maps the presets of the time tag option to Go layouts.
+var timeLayouts = map[string]string{
+	"YYYYMMDD":       "20060102",
+	"YYMMDD":         "060102",
+	"HHMMSS":         "150405",
+	"YYYYMMDDHHMMSS": "20060102150405",
+}
+
+// timeUnix is the preset of the time tag option for Unix seconds.
+const timeUnix = "unix"
+
+// A partialDate is a date format that omits (part of) the year. Partial dates are
+// resolved to the date nearest to a reference date.
+type partialDate int
+
+const (
+	partialNone partialDate = iota
+	partialMMDD             // month and day, e.g. "0426"
+	partialYDDD             // last digit of the year and day of the year, e.g. "9116"
+)
+
+// partialDates maps the presets of the time tag option to partial date formats.
+var partialDates = map[string]partialDate{
+	"MMDD": partialMMDD,
+	"YDDD": partialYDDD,
+}
+
+// timeStyle describes how a time field is encoded: with a Go layout, e.g.
+// "2006-01-02", as Unix seconds, or as a partial date. Times are decoded in loc, and
+// encoded in loc if it is set.
+type timeStyle struct {
+	layout  string
+	unix    bool
+	partial partialDate
+	loc     *time.Location
+}
+
+// newTimeStyle returns the style described by the time and tz tag options. The
+// default layout is RFC 3339, as used by time.Time's MarshalText.
+func newTimeStyle(layout, tz string) (timeStyle, error) {
+	s := timeStyle{layout: time.RFC3339Nano}
+	switch {
+	case layout == timeUnix:
+		s.unix = true
+	case timeLayouts[layout] != "":
+		s.layout = timeLayouts[layout]
+	case partialDates[layout] != partialNone:
+		s.partial = partialDates[layout]
+	case layout != "":
+		s.layout = layout
+	}
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return timeStyle{}, err
+		}
+		s.loc = loc
+	}
+	return s, nil
+}
+
+// location returns the location times are decoded in.
+func (s timeStyle) location() *time.Location {
+	if s.loc == nil {
+		return time.UTC
+	}
+	return s.loc
+}
+
+// parse decodes a time. Partial dates are resolved against the date of ref. Blank and
+// all-zero values are the zero time, and ok is false.
+func (s timeStyle) parse(value string, ref time.Time) (t time.Time, ok bool, err error) {
+	value = strings.TrimSpace(value)
+	if strings.Trim(value, "0") == "" {
+		return time.Time{}, false, nil
+	}
+	loc := s.location()
+	if s.partial != partialNone {
+		t, err = s.resolve(value, ref)
+		return t, err == nil, err
+	}
+	if s.unix {
+		sec, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return time.Unix(sec, 0).In(loc), true, nil
+	}
+	t, err = time.ParseInLocation(s.layout, value, loc)
+	return t, err == nil, err
+}
+
+// format encodes a time. The zero time is encoded as an empty value.
+func (s timeStyle) format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if s.loc != nil {
+		t = t.In(s.loc)
+	}
+	switch {
+	case s.unix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case s.partial == partialMMDD:
+		return t.Format("0102")
+	case s.partial == partialYDDD:
+		return strconv.Itoa(t.Year()%10) + t.Format("002")
+	}
+	return t.Format(s.layout)
+}
+
+// resolve decodes a partial date as the date nearest to the date of ref, so that
+// e.g. "1231" is in the year before a reference date of January 2.
+func (s timeStyle) resolve(value string, ref time.Time) (time.Time, error) {
+	invalid := errors.New("fixedwidth: invalid partial date " + strconv.Quote(value))
+	if len(value) != 4 {
+		return time.Time{}, invalid
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return time.Time{}, invalid
+	}
+
+	loc := s.location()
+	ref = time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, loc)
+	var years [3]int
+	switch s.partial {
+	case partialMMDD:
+		years = [3]int{ref.Year() - 1, ref.Year(), ref.Year() + 1}
+	case partialYDDD:
+		year := ref.Year() - ref.Year()%10 + n/1000
+		years = [3]int{year - 10, year, year + 10}
+	}
+
+	var date time.Time
+	for _, year := range years {
+		var t time.Time
+		switch s.partial {
+		case partialMMDD:
+			month, day := n/100, n%100
+			t = time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+			if t.Month() != time.Month(month) || t.Day() != day {
+				// E.g. February 29 of a year that is not a leap year.
+				continue
+			}
+		case partialYDDD:
+			day := n % 1000
+			t = time.Date(year, 1, day, 0, 0, 0, 0, loc)
+			if day == 0 || t.Year() != year {
+				continue
+			}
+		}
+		if date.IsZero() || absDuration(t.Sub(ref)) < absDuration(date.Sub(ref)) {
+			date = t
+		}
+	}
+	if date.IsZero() {
+		return time.Time{}, invalid
+	}
+	return date, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// reference returns the date that partial dates are resolved against when a field
+// does not have a reference field: the reference date of the Decoder that raw was
+// read by, or the current date.
+func reference(raw rawValue) time.Time {
+	if raw.state == nil || raw.state.reference.IsZero() {
+		return time.Now()
+	}
+	return raw.state.reference
+}
+
+// timeSetter decodes a time.Time or *time.Time field. Blank values set a pointer to
+// nil and a time.Time to the zero time.
+func timeSetter(s timeStyle) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		return setTime(v, raw, s, reference(raw))
+	}
+}
+
+// setTime decodes raw into the time.Time or *time.Time v, resolving partial dates
+// against ref.
+func setTime(v reflect.Value, raw rawValue, s timeStyle, ref time.Time) error {
+	t, ok, err := s.parse(raw.data, ref)
+	if err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Ptr {
+		if !ok {
+			return nilSetter(v, raw)
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(timeType))
+		}
+		v = v.Elem()
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// timeEncoder encodes a time.Time or *time.Time field.
+func timeEncoder(s timeStyle) valueEncoder {
+	return func(v reflect.Value) (rawValue, error) {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nilEncoder(v)
+			}
+			v = v.Elem()
+		}
+		return newRawValue(s.format(v.Interface().(time.Time)), false)
+	}
+}
+
+// newTimeCodec returns the encoder and setter of a time field with the given time and
+// tz tag options. An unknown time zone is reported when the field is used.
+func newTimeCodec(layout, tz string) (valueEncoder, valueSetter) {
+	s, err := newTimeStyle(layout, tz)
+	if err != nil {
+		err = unknownTimeZoneError(tz)
+		return func(reflect.Value) (rawValue, error) { return rawValue{}, err },
+			func(reflect.Value, rawValue) error { return err }
+	}
+	return timeEncoder(s), timeSetter(s)
+}
+
+func unknownTimeZoneError(tz string) error {
+	return errors.New("fixedwidth: unknown time zone " + tz)
+}
+
+// dateReference links a partial date field to the time field of the same struct that
+// holds its reference date, e.g. the processing date of a file.
+type dateReference struct {
+	field string
+	index int // index of the reference field, or -1 if it does not exist
+	style timeStyle
+	err   error
+}
+
+// newDateReference returns the dateReference of a time field of the struct t with the
+// given options.
+func newDateReference(t reflect.Type, opts fieldOptions) *dateReference {
+	r := &dateReference{field: opts.ref, index: -1}
+	if sf, ok := t.FieldByName(opts.ref); ok && len(sf.Index) == 1 && indirectType(sf.Type) == timeType {
+		r.index = sf.Index[0]
+	}
+	var err error
+	if r.style, err = newTimeStyle(opts.time, opts.tz); err != nil {
+		r.err = unknownTimeZoneError(opts.tz)
+	}
+	return r
+}
+
+// setterFor returns the setter of the partial date field of line, a line of the
+// struct ss. If the reference field is blank, the date is resolved as if the field
+// had no reference field.
+func (r *dateReference) setterFor(ss structSpec, line rawValue) valueSetter {
+	return func(v reflect.Value, raw rawValue) error {
+		if r.err != nil {
+			return r.err
+		}
+		if r.index < 0 || !ss.fieldSpecs[r.index].ok {
+			return errors.New("fixedwidth: reference date field " + r.field + " not found")
+		}
+		spec := ss.fieldSpecs[r.index]
+
+		var ref time.Time
+		if err := spec.setter(reflect.ValueOf(&ref).Elem(), rawValueFromLine(line, spec.startPos, spec.endPos, spec.format)); err != nil {
+			return err
+		}
+		if ref.IsZero() {
+			ref = reference(raw)
+		}
+		return setTime(v, raw, r.style, ref)
+	}
+}
@@ -0,0 +1,236 @@
+package fixedwidth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	type H struct {
+		Date     time.Time  `fixed:"1,8,time=YYYYMMDD"`
+		Short    *time.Time `fixed:"9,14,time=YYMMDD"`
+		Clock    time.Time  `fixed:"15,20,time=HHMMSS"`
+		Stamp    time.Time  `fixed:"21,34,time=YYYYMMDDHHMMSS,tz=America/New_York"`
+		Unix     time.Time  `fixed:"35,44,time=unix"`
+		Layout   time.Time  `fixed:"45,54,time=2006-01-02"`
+		Standard time.Time  `fixed:"55,74"`
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available:", err)
+	}
+	date := time.Date(2021, 4, 26, 0, 0, 0, 0, time.UTC)
+	stamp := time.Date(2021, 4, 26, 13, 45, 30, 0, ny)
+
+	for _, tt := range []struct {
+		name string
+		data string
+		want H
+	}{
+		{
+			name: "values",
+			data: "20210426210426134530202104261345301619459130" + "2021-04-262021-04-26T13:45:30Z",
+			want: H{
+				Date:     date,
+				Short:    &date,
+				Clock:    time.Date(0, 1, 1, 13, 45, 30, 0, time.UTC),
+				Stamp:    stamp,
+				Unix:     stamp.In(time.UTC),
+				Layout:   date,
+				Standard: time.Date(2021, 4, 26, 13, 45, 30, 0, time.UTC),
+			},
+		},
+		{
+			name: "blank",
+			data: "        " + "      " + "      " + "              " + "          " + "          " + "                    ",
+			want: H{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var have H
+			if err := Unmarshal([]byte(tt.data), &have); err != nil {
+				t.Fatalf("Unmarshal(%q) unexpected error: %v", tt.data, err)
+			}
+			if !eqTime(have.Date, tt.want.Date) || !eqTimePtr(have.Short, tt.want.Short) ||
+				!eqTime(have.Clock, tt.want.Clock) || !eqTime(have.Stamp, tt.want.Stamp) ||
+				!eqTime(have.Unix, tt.want.Unix) || !eqTime(have.Layout, tt.want.Layout) ||
+				!eqTime(have.Standard, tt.want.Standard) {
+				t.Errorf("Unmarshal(%q) want %+v, have %+v", tt.data, tt.want, have)
+			}
+			if loc := have.Stamp.Location(); !have.Stamp.IsZero() && loc.String() != ny.String() {
+				t.Errorf("Unmarshal(%q) want location %v, have %v", tt.data, ny, loc)
+			}
+
+			o, err := Marshal(tt.want)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+			if string(o) != tt.data {
+				t.Errorf("Marshal() want %q, have %q", tt.data, o)
+			}
+		})
+	}
+
+	t.Run("zeros", func(t *testing.T) {
+		have := H{Short: &date, Date: date}
+		if err := Unmarshal([]byte("00000000000000"), &have); err != nil {
+			t.Fatalf("Unmarshal() unexpected error: %v", err)
+		}
+		if !have.Date.IsZero() || have.Short != nil {
+			t.Errorf("Unmarshal() want zero time and nil, have %v, %v", have.Date, have.Short)
+		}
+	})
+
+	t.Run("convert to tz", func(t *testing.T) {
+		o, err := Marshal(H{Stamp: time.Date(2021, 4, 26, 17, 45, 30, 0, time.UTC)})
+		if want := "20210426134530"; err != nil || string(o[20:34]) != want {
+			t.Errorf("Marshal() want %q, have %q, %v", want, o[20:34], err)
+		}
+	})
+}
+
+func TestTime_errors(t *testing.T) {
+	type H struct {
+		Date time.Time `fixed:"1,8,time=YYYYMMDD"`
+	}
+	type Z struct {
+		Date time.Time `fixed:"1,8,time=YYYYMMDD,tz=Nowhere/Special"`
+	}
+
+	var h H
+	if err := Unmarshal([]byte("20211340"), &h); err == nil {
+		t.Errorf("Unmarshal() want error for an invalid date")
+	}
+
+	var z Z
+	if err := Unmarshal([]byte("20210426"), &z); err == nil {
+		t.Errorf("Unmarshal() want error for an unknown time zone")
+	}
+	if _, err := Marshal(z); err == nil {
+		t.Errorf("Marshal() want error for an unknown time zone")
+	}
+}
+
+func eqTime(a, b time.Time) bool {
+	return a.Equal(b)
+}
+
+func eqTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestTimeStyle_resolve(t *testing.T) {
+	ref := time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		partial partialDate
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{partialMMDD, "0102", time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{partialMMDD, "0426", time.Date(2021, 4, 26, 0, 0, 0, 0, time.UTC), false},
+		{partialMMDD, "1231", time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), false},
+		{partialMMDD, "0229", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC), false},
+		{partialMMDD, "1301", time.Time{}, true},
+		{partialMMDD, "0431", time.Time{}, true},
+		{partialYDDD, "1002", time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{partialYDDD, "0366", time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), false},
+		{partialYDDD, "9116", time.Date(2019, 4, 26, 0, 0, 0, 0, time.UTC), false},
+		{partialYDDD, "7001", time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{partialYDDD, "5001", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{partialYDDD, "1366", time.Time{}, true},
+		{partialYDDD, "1000", time.Time{}, true},
+		{partialYDDD, "12", time.Time{}, true},
+		{partialYDDD, "1a02", time.Time{}, true},
+	} {
+		have, err := timeStyle{partial: tt.partial}.resolve(tt.value, ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("resolve(%q) want error, have %v", tt.value, have)
+			}
+			continue
+		}
+		if err != nil || !have.Equal(tt.want) {
+			t.Errorf("resolve(%q) want %v, have %v, %v", tt.value, tt.want, have, err)
+		}
+	}
+}
+
+func TestTime_partial(t *testing.T) {
+	type H struct {
+		Purchase   time.Time  `fixed:"1,4,time=MMDD,ref=Processing"`
+		Processing *time.Time `fixed:"5,8,time=YDDD"`
+		Settled    time.Time  `fixed:"9,16,time=YYYYMMDD"`
+		Posted     time.Time  `fixed:"17,20,time=MMDD,ref=Settled"`
+	}
+
+	decode := func(data string) (H, error) {
+		var h H
+		dec := NewDecoder(strings.NewReader(data))
+		dec.SetReferenceDate(time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC))
+		err := dec.Decode(&h)
+		return h, err
+	}
+
+	h, err := decode("12300003202101021231")
+	if err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	for _, tt := range []struct {
+		name       string
+		have, want time.Time
+	}{
+		// Processing is resolved against the reference date of the Decoder, and
+		// Purchase against Processing.
+		{"Processing", *h.Processing, time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"Purchase", h.Purchase, time.Date(2019, 12, 30, 0, 0, 0, 0, time.UTC)},
+		{"Posted", h.Posted, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)},
+	} {
+		if !tt.have.Equal(tt.want) {
+			t.Errorf("%s want %v, have %v", tt.name, tt.want, tt.have)
+		}
+	}
+
+	o, err := Marshal(h)
+	if want := "12300003202101021231"; err != nil || string(o) != want {
+		t.Errorf("Marshal() want %q, have %q, %v", want, o, err)
+	}
+
+	t.Run("blank reference", func(t *testing.T) {
+		h, err := decode("1230    ")
+		if want := time.Date(2019, 12, 30, 0, 0, 0, 0, time.UTC); err != nil || !h.Purchase.Equal(want) || h.Processing != nil {
+			t.Errorf("Decode() want %v, have %v, %v", want, h.Purchase, err)
+		}
+	})
+
+	t.Run("reference date", func(t *testing.T) {
+		// The reference date is read when decoding, and does not build new specs.
+		n := cachedStructSpecs()
+		dec := NewDecoder(strings.NewReader("12300003202101021231\n12300003202101021231"))
+		for _, year := range []int{2020, 2030} {
+			dec.SetReferenceDate(time.Date(year, 1, 5, 0, 0, 0, 0, time.UTC))
+			var h H
+			if err := dec.Decode(&h); err != nil || h.Processing.Year() != year {
+				t.Errorf("Decode() want processing date in %d, have %v, %v", year, h.Processing, err)
+			}
+		}
+		if have := cachedStructSpecs(); have != n {
+			t.Errorf("Decode() cached %d new struct specs", have-n)
+		}
+	})
+
+	t.Run("missing reference", func(t *testing.T) {
+		type M struct {
+			Purchase time.Time `fixed:"1,4,time=MMDD,ref=Missing"`
+		}
+		var m M
+		if err := Unmarshal([]byte("1230"), &m); err == nil {
+			t.Errorf("Unmarshal() want error for a missing reference field")
+		}
+	})
+}
@@ -1,48 +1,49 @@
 package fixedwidth
 
 import (
+	"bytes"
 	"os"
 	"testing"
+	"time"
 )
 
 type VISA_TC05_TCR0 struct {
-	TransactionCode int `fixed:"1,2"`
-	// TODO : Handle MessageHashTotal as a byte array
-	MessageHashTotal                                string `fixed:"3,4"`
-	TransactionCodeQualifier                        int    `fixed:"5,5"`
-	TransactionComponentSequenceNumber              int    `fixed:"6,6"`
-	AccountNumber                                   int    `fixed:"7,22"`
-	AccountNumberExtension                          int    `fixed:"23,25"`
-	FloorLimitIndicator                             string `fixed:"26,26,none,_"` // disable padding
-	CRBExceptionFileIndicator                       string `fixed:"27,27,none,_"` // disable padding
-	PositiveCardholderAuthorizationServiceIndicator string `fixed:"28,28,none,_"` // disable padding
-	AcquirerReferenceNumber                         string `fixed:"29,51"`
-	AcquirerBusinessID                              string `fixed:"52,59"`
-	PurchaseDate                                    string `fixed:"60,63"`
-	DestinationAmount                               string `fixed:"64,75"`
-	DestinationCurrencyCode                         string `fixed:"76,78"`
-	SourceAmount                                    string `fixed:"79,90"`
-	SourceCurrencyCode                              string `fixed:"91,93"`
-	MerchantName                                    string `fixed:"94,118"`
-	MerchantCity                                    string `fixed:"119,131"`
-	MerchantCountryCode                             string `fixed:"132,134"`
-	MerchantCategoryCode                            string `fixed:"135,138"`
-	MerchantZIPCode                                 string `fixed:"139,143"`
-	MerchantStateProvinceCode                       string `fixed:"144,146"`
-	RequestedPaymentService                         string `fixed:"147,147,none,_"` // disable padding
-	NumberOfPaymentForms                            string `fixed:"148,148,none,_"` // disable padding
-	UsageCode                                       int    `fixed:"149,149"`
-	ReasonCode                                      string `fixed:"150,151"`
-	SettlementFlag                                  int    `fixed:"152,152"`
-	AuthorizationCharacteristicsIndicator           string `fixed:"153,153"`
-	AuthorizationCode                               string `fixed:"154,159"`
-	POSTerminalCapability                           string `fixed:"160,160,none,_"` // disable padding
-	ReservedField1                                  string `fixed:"161,161,none,_"` // disable padding
-	CardholderIDMethod                              string `fixed:"162,162,none,_"` // disable padding
-	CollectionOnlyFlag                              string `fixed:"163,163,none,_"` // disable padding
-	POSEntryMode                                    string `fixed:"164,165,none,_"` // disable padding
-	CentralProcessingDate                           string `fixed:"166,169"`
-	ReimbursementAttribute                          string `fixed:"170,170,none,_"` // disable padding
+	TransactionCode                                 int       `fixed:"1,2"`
+	MessageHashTotal                                []byte    `fixed:"3,4"`
+	TransactionCodeQualifier                        int       `fixed:"5,5"`
+	TransactionComponentSequenceNumber              int       `fixed:"6,6"`
+	AccountNumber                                   int       `fixed:"7,22"`
+	AccountNumberExtension                          int       `fixed:"23,25"`
+	FloorLimitIndicator                             string    `fixed:"26,26,none,_"` // disable padding
+	CRBExceptionFileIndicator                       string    `fixed:"27,27,none,_"` // disable padding
+	PositiveCardholderAuthorizationServiceIndicator string    `fixed:"28,28,none,_"` // disable padding
+	AcquirerReferenceNumber                         string    `fixed:"29,51"`
+	AcquirerBusinessID                              string    `fixed:"52,59"`
+	PurchaseDate                                    time.Time `fixed:"60,63,time=MMDD,ref=CentralProcessingDate"`
+	DestinationAmount                               Money     `fixed:"64,75,currency=DestinationCurrencyCode"`
+	DestinationCurrencyCode                         string    `fixed:"76,78"`
+	SourceAmount                                    Money     `fixed:"79,90,currency=SourceCurrencyCode"`
+	SourceCurrencyCode                              string    `fixed:"91,93"`
+	MerchantName                                    string    `fixed:"94,118"`
+	MerchantCity                                    string    `fixed:"119,131"`
+	MerchantCountryCode                             string    `fixed:"132,134"`
+	MerchantCategoryCode                            string    `fixed:"135,138"`
+	MerchantZIPCode                                 string    `fixed:"139,143"`
+	MerchantStateProvinceCode                       string    `fixed:"144,146"`
+	RequestedPaymentService                         string    `fixed:"147,147,none,_"` // disable padding
+	NumberOfPaymentForms                            string    `fixed:"148,148,none,_"` // disable padding
+	UsageCode                                       int       `fixed:"149,149"`
+	ReasonCode                                      string    `fixed:"150,151"`
+	SettlementFlag                                  int       `fixed:"152,152"`
+	AuthorizationCharacteristicsIndicator           string    `fixed:"153,153"`
+	AuthorizationCode                               string    `fixed:"154,159"`
+	POSTerminalCapability                           string    `fixed:"160,160,none,_"` // disable padding
+	ReservedField1                                  string    `fixed:"161,161,none,_"` // disable padding
+	CardholderIDMethod                              string    `fixed:"162,162,none,_"` // disable padding
+	CollectionOnlyFlag                              string    `fixed:"163,163,none,_"` // disable padding
+	POSEntryMode                                    string    `fixed:"164,165,none,_"` // disable padding
+	CentralProcessingDate                           time.Time `fixed:"166,169,time=YDDD"`
+	ReimbursementAttribute                          string    `fixed:"170,170,none,_"` // disable padding
 }
 
 func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
@@ -53,10 +54,11 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 		t.Fatalf("Failed to read test file: %v", err)
 	}
 
-	// Convert to string and parse
-	record := string(data)
+	// Parse, resolving partial dates against the processing date of the file
 	var visa VISA_TC05_TCR0
-	err = Unmarshal([]byte(record), &visa)
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.SetReferenceDate(time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC))
+	err = dec.Decode(&visa)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal record: %v", err)
 	}
@@ -64,7 +66,7 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 	// Verify parsed data matches expected values
 	expected := VISA_TC05_TCR0{
 		TransactionCode:                                 25,
-		MessageHashTotal:                                string([]byte{28, 133}), // Represented as �� in the file
+		MessageHashTotal:                                []byte{28, 133}, // Represented as �� in the file
 		TransactionCodeQualifier:                        0,
 		TransactionComponentSequenceNumber:              0,
 		AccountNumber:                                   4830970000162705,
@@ -74,10 +76,10 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 		PositiveCardholderAuthorizationServiceIndicator: " ",
 		AcquirerReferenceNumber:                         "74064499116000000155872",
 		AcquirerBusinessID:                              "10021249",
-		PurchaseDate:                                    "0426",
-		DestinationAmount:                               "000000003042",
+		PurchaseDate:                                    time.Date(2019, 4, 26, 0, 0, 0, 0, time.UTC),
+		DestinationAmount:                               Money{Decimal{3042, 2}, "840"},
 		DestinationCurrencyCode:                         "840",
-		SourceAmount:                                    "000000004001",
+		SourceAmount:                                    Money{Decimal{4001, 2}, "124"},
 		SourceCurrencyCode:                              "124",
 		MerchantName:                                    "MERCHANT NAME",
 		MerchantCity:                                    "MERCHANT CITY",
@@ -97,7 +99,7 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 		CardholderIDMethod:                              "1",
 		CollectionOnlyFlag:                              " ",
 		POSEntryMode:                                    "05",
-		CentralProcessingDate:                           "9116",
+		CentralProcessingDate:                           time.Date(2019, 4, 26, 0, 0, 0, 0, time.UTC),
 		ReimbursementAttribute:                          "B",
 	}
 
@@ -106,8 +108,8 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 		t.Errorf("TransactionCode: got %d, want %d", visa.TransactionCode, expected.TransactionCode)
 	}
 
-	if string(visa.MessageHashTotal) != string(expected.MessageHashTotal) {
-		t.Errorf("MessageHashTotal: got %v, want %v", []byte(visa.MessageHashTotal), []byte(expected.MessageHashTotal))
+	if !bytes.Equal(visa.MessageHashTotal, expected.MessageHashTotal) {
+		t.Errorf("MessageHashTotal: got %v, want %v", visa.MessageHashTotal, expected.MessageHashTotal)
 	}
 
 	if visa.TransactionCodeQualifier != expected.TransactionCodeQualifier {
@@ -146,12 +148,12 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 		t.Errorf("AcquirerBusinessID: got %s, want %s", visa.AcquirerBusinessID, expected.AcquirerBusinessID)
 	}
 
-	if visa.PurchaseDate != expected.PurchaseDate {
-		t.Errorf("PurchaseDate: got %s, want %s", visa.PurchaseDate, expected.PurchaseDate)
+	if !visa.PurchaseDate.Equal(expected.PurchaseDate) {
+		t.Errorf("PurchaseDate: got %v, want %v", visa.PurchaseDate, expected.PurchaseDate)
 	}
 
 	if visa.DestinationAmount != expected.DestinationAmount {
-		t.Errorf("DestinationAmount: got %s, want %s", visa.DestinationAmount, expected.DestinationAmount)
+		t.Errorf("DestinationAmount: got %v, want %v", visa.DestinationAmount, expected.DestinationAmount)
 	}
 
 	if visa.DestinationCurrencyCode != expected.DestinationCurrencyCode {
@@ -159,7 +161,7 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 	}
 
 	if visa.SourceAmount != expected.SourceAmount {
-		t.Errorf("SourceAmount: got %s, want %s", visa.SourceAmount, expected.SourceAmount)
+		t.Errorf("SourceAmount: got %v, want %v", visa.SourceAmount, expected.SourceAmount)
 	}
 
 	if visa.SourceCurrencyCode != expected.SourceCurrencyCode {
@@ -238,7 +240,7 @@ func TestVISA_TC05_TCR0_Parse_Test(t *testing.T) {
 		t.Errorf("POSEntryMode: got %s, want %s", visa.POSEntryMode, expected.POSEntryMode)
 	}
 
-	if visa.CentralProcessingDate != expected.CentralProcessingDate {
+	if !visa.CentralProcessingDate.Equal(expected.CentralProcessingDate) {
 		t.Errorf("CentralProcessingDate: got %v, want %v", visa.CentralProcessingDate, expected.CentralProcessingDate)
 	}
 
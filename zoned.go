@@ -0,0 +1,178 @@
+package fixedwidth
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// zonedStyle describes how a zoned decimal field is encoded. Zoned decimals store one
+// digit per byte and overpunch the sign on the first or last digit.
+//
+// In ASCII overpunch, the signed digits 0-9 are written as '{', 'A'-'I' when positive
+// and '}', 'J'-'R' when negative. In EBCDIC zoned decimal, digits have the zone 0xF
+// and the zone of the signed digit is 0xC when positive and 0xD when negative.
+type zonedStyle struct {
+	ebcdic  bool
+	leading bool
+}
+
+const (
+	overpunchPositive = "{ABCDEFGHI"
+	overpunchNegative = "}JKLMNOPQR"
+)
+
+// digit decodes an unsigned digit.
+func (z zonedStyle) digit(x byte) (int64, bool) {
+	if z.ebcdic {
+		x -= 0xF0
+	} else {
+		x -= '0'
+	}
+	return int64(x), x <= 9
+}
+
+// signedDigit decodes a digit that may carry an overpunched sign.
+func (z zonedStyle) signedDigit(x byte) (digit int64, negative, ok bool) {
+	if z.ebcdic {
+		digit = int64(x & 0x0F)
+		switch x >> 4 {
+		case 0xB, 0xD:
+			negative = true
+		case 0xA, 0xC, 0xE, 0xF:
+		default:
+			return 0, false, false
+		}
+		return digit, negative, digit <= 9
+	}
+
+	if d, ok := z.digit(x); ok {
+		return d, false, true
+	}
+	for i := 0; i < 10; i++ {
+		switch x {
+		case overpunchPositive[i]:
+			return int64(i), false, true
+		case overpunchNegative[i]:
+			return int64(i), true, true
+		}
+	}
+	return 0, false, false
+}
+
+// encodeDigit encodes a digit. If signed is true, the sign is overpunched on it.
+func (z zonedStyle) encodeDigit(digit int64, signed, negative bool) byte {
+	switch {
+	case z.ebcdic && !signed:
+		return 0xF0 | byte(digit)
+	case z.ebcdic && negative:
+		return 0xD0 | byte(digit)
+	case z.ebcdic:
+		return 0xC0 | byte(digit)
+	case !signed:
+		return '0' + byte(digit)
+	case negative:
+		return overpunchNegative[digit]
+	default:
+		return overpunchPositive[digit]
+	}
+}
+
+// parse decodes a zoned decimal with the given scale. A field of spaces is blank.
+func (z zonedStyle) parse(b []byte, scale int) (d Decimal, ok bool, err error) {
+	if isBlank(b) {
+		return Decimal{}, false, nil
+	}
+	signIndex := len(b) - 1
+	if z.leading {
+		signIndex = 0
+	}
+
+	var (
+		c        int64
+		negative bool
+	)
+	for i, x := range b {
+		var digit int64
+		if i == signIndex {
+			digit, negative, ok = z.signedDigit(x)
+		} else {
+			digit, ok = z.digit(x)
+		}
+		if !ok {
+			return Decimal{}, false, &strconv.NumError{Func: "parseZoned", Num: string(b), Err: strconv.ErrSyntax}
+		}
+		if c > (math.MaxInt64-digit)/10 {
+			return Decimal{}, false, errDecimalRange
+		}
+		c = c*10 + digit
+	}
+	if negative {
+		c = -c
+	}
+	return Decimal{Coefficient: c, Scale: scale}, true, nil
+}
+
+// format encodes the coefficient of d as a zoned decimal of width bytes, one digit per
+// byte. If unsigned is true, no sign is overpunched.
+func (z zonedStyle) format(d Decimal, width int, unsigned bool) ([]byte, error) {
+	c := d.Coefficient
+	negative := c < 0
+	if negative && unsigned {
+		return nil, errDecimalRange
+	}
+	if n := countDigits(c); n > width {
+		return nil, &OverflowError{Width: width, Len: n}
+	}
+
+	signIndex := width - 1
+	if z.leading {
+		signIndex = 0
+	}
+	b := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		digit := c % 10
+		if digit < 0 {
+			digit = -digit
+		}
+		c /= 10
+		b[i] = z.encodeDigit(digit, !unsigned && i == signIndex, negative)
+	}
+	return b, nil
+}
+
+// isBlank reports whether b is empty or consists of ASCII or EBCDIC spaces.
+func isBlank(b []byte) bool {
+	for _, x := range b {
+		if x != ' ' && x != 0x40 {
+			return false
+		}
+	}
+	return true
+}
+
+// zonedSetter decodes a zoned decimal field with the given scale.
+func zonedSetter(z zonedStyle, scale int, c codecConfig) valueSetter {
+	return numericSetter(func(b []byte) (Decimal, bool, error) {
+		return z.parse(b, scale)
+	}, zonedConfig(z, c))
+}
+
+// zonedEncoder encodes a zoned decimal field of width digits with the given scale.
+// Unsigned integers are encoded without a sign.
+func zonedEncoder(t reflect.Type, z zonedStyle, width, scale int, mode roundingMode, c codecConfig) valueEncoder {
+	unsigned := isUnsignedKind(t)
+	return numericEncoder(scale, mode, func(d Decimal) ([]byte, error) {
+		return z.format(d, width, unsigned)
+	}, zonedConfig(z, c))
+}
+
+// zonedConfig returns the config used for the bytes of a zoned field. ASCII overpunch
+// is text, so it is translated with the rest of a translated stream; the letters
+// used for the signed digits correspond to EBCDIC zoned decimal.
+func zonedConfig(z zonedStyle, c codecConfig) codecConfig {
+	if !z.ebcdic {
+		c.codePage = nil
+	}
+	return c
+}
@@ -0,0 +1,90 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestZoned(t *testing.T) {
+	type H struct {
+		Trailing int     `fixed:"1,4,overpunch"`
+		Leading  int     `fixed:"5,8,overpunch=leading"`
+		Scaled   float64 `fixed:"9,13,overpunch,scale=2"`
+		Unsigned uint    `fixed:"14,16,overpunch"`
+		Ebcdic   int     `fixed:"17,19,zoned"`
+		EbcdicL  Decimal `fixed:"20,22,zoned=leading,scale=1"`
+		Blank    *int    `fixed:"23,25,overpunch"`
+	}
+
+	data := append([]byte("012J"+"{123"+"0304}"+"042"), 0xF1, 0xF2, 0xD3, 0xC4, 0xF5, 0xF6)
+	data = append(data, "   "...)
+	want := H{-121, 123, -30.40, 42, -123, Decimal{456, 1}, nil}
+
+	var have H
+	if err := Unmarshal(data, &have); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if have != want {
+		t.Errorf("Unmarshal() want %+v, have %+v", want, have)
+	}
+
+	o, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, o) {
+		t.Errorf("Marshal() want %q, have %q", data, o)
+	}
+}
+
+func TestZoned_errors(t *testing.T) {
+	type H struct {
+		F1 int `fixed:"1,3,overpunch"`
+	}
+
+	var v H
+	err := Unmarshal([]byte("1X3"), &v)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "F1" {
+		t.Errorf("Unmarshal() want *UnmarshalTypeError, have %v", err)
+	}
+
+	_, err = Marshal(H{F1: 1234})
+	var overflowErr *OverflowError
+	if !errors.As(err, &overflowErr) || overflowErr.Field != "F1" || overflowErr.Width != 3 || overflowErr.Len != 4 {
+		t.Errorf("Marshal() want *OverflowError, have %v", err)
+	}
+}
+
+func TestZoned_stream(t *testing.T) {
+	type H struct {
+		Overpunch int `fixed:"1,3,overpunch"`
+		Zoned     int `fixed:"4,6,zoned"`
+	}
+
+	// In a translated stream, the ASCII overpunch letters correspond to the EBCDIC
+	// zoned decimal bytes.
+	data := []byte{0xF1, 0xF2, 0xD3, 0xF4, 0xF5, 0xC6}
+	want := H{-123, 456}
+
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.SetCodePage(CodePage037)
+	var have H
+	if err := dec.Decode(&have); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if have != want {
+		t.Errorf("Decode() want %+v, have %+v", want, have)
+	}
+
+	buff := new(bytes.Buffer)
+	enc := NewEncoder(buff)
+	enc.SetCodePage(CodePage037)
+	if err := enc.Encode(want); err != nil {
+		t.Fatalf("Encode() unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, buff.Bytes()) {
+		t.Errorf("Encode() want %X, have %X", data, buff.Bytes())
+	}
+}